@@ -0,0 +1,353 @@
+// Package callgraph builds call graphs of one or more programs walked by the walker.
+package callgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+)
+
+// Node is a function, or the top level code of a program
+type Node struct {
+	Index int
+	// Function is nil for the top level code of the program
+	Function *ast.FunctionLiteral
+	Program  *ast.Program
+	Name     string
+	Callers  []*Edge
+	Callees  []*Edge
+}
+
+// Edge is a call site of a function
+type Edge struct {
+	Caller *Node
+	Callee *Node
+	// Site is the *ast.CallExpression or *ast.NewExpression
+	Site ast.Expression
+}
+
+// Graph is a call graph.
+// Functions are resolved through the scopes of the callers, following variables aliasing functions.
+// Global variables are shared by all programs of the graph.
+type Graph struct {
+	Nodes []*Node
+	Edges []*Edge
+	// Unresolved lists the call sites whose callee is not known, e.g. method calls
+	Unresolved []ast.Expression
+
+	functions map[*ast.FunctionLiteral]*Node
+	programs  map[*ast.Program]*Node
+	values    map[key][]value
+	sites     []site
+}
+
+// key identifies a variable, the scope of globals is nil
+type key struct {
+	scope ast.Node
+	name  string
+}
+
+// value assigned to a variable, either a function or another variable
+type value struct {
+	function *ast.FunctionLiteral
+	alias    *key
+}
+
+// site is a call found by the walker
+type site struct {
+	caller *Node
+	call   ast.Expression
+	callee ast.Expression
+	// variable is the variable called, nil if the callee is not an identifier
+	variable *key
+	unknown  bool
+}
+
+// Build returns the call graph of the programs
+func Build(programs ...*ast.Program) *Graph {
+	g := &Graph{
+		functions: map[*ast.FunctionLiteral]*Node{},
+		programs:  map[*ast.Program]*Node{},
+		values:    map[key][]value{},
+	}
+
+	for _, program := range programs {
+		g.add(program)
+	}
+	g.resolve()
+
+	return g
+}
+
+// add walks the program, collecting its functions, variables and call sites
+func (g *Graph) add(program *ast.Program) {
+	name := "program"
+	if program.File != nil && program.File.Name() != "" {
+		name = program.File.Name()
+	}
+	g.programs[program] = g.newNode(nil, program, name)
+
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(&walker.Hook{
+		OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+			g.onNode(program, node, metadata)
+			return nil
+		},
+	})
+	walker.NewWalker(visitor).Begin(program)
+}
+
+func (g *Graph) newNode(function *ast.FunctionLiteral, program *ast.Program, name string) *Node {
+	node := &Node{Index: len(g.Nodes), Function: function, Program: program, Name: name}
+	g.Nodes = append(g.Nodes, node)
+	if function != nil {
+		g.functions[function] = node
+	}
+
+	return node
+}
+
+// FunctionName returns the name of the function, function@line:column if it is anonymous,
+// or function@index if the file of the program is not known
+func FunctionName(program *ast.Program, function *ast.FunctionLiteral) string {
+	if function.Name != nil {
+		return function.Name.Name
+	}
+	if program != nil && program.File != nil {
+		if position := program.File.Position(function.Idx0()); position != nil {
+			return fmt.Sprintf("function@%v:%v", position.Line, position.Column)
+		}
+	}
+
+	return fmt.Sprintf("function@%v", function.Idx0())
+}
+
+func (g *Graph) onNode(program *ast.Program, node ast.Node, metadata []walker.Metadata) {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		g.newNode(n, program, FunctionName(program, n))
+
+		// The name of a function expression is visible in its own scope
+		if n.Name != nil {
+			if _, isStatement := walker.ParentMetadata(metadata).Node().(*ast.FunctionStatement); !isStatement {
+				variable := key{n, n.Name.Name}
+				g.values[variable] = append(g.values[variable], value{function: n})
+			}
+		}
+	case *ast.FunctionStatement:
+		if variable := variableKey(metadata, n.Function.Name.Name); variable != nil {
+			g.assign(*variable, n.Function, metadata)
+		}
+	case *ast.VariableExpression:
+		if variable := variableKey(metadata, n.Name); variable != nil && n.Initializer != nil {
+			g.assign(*variable, n.Initializer, metadata)
+		}
+	case *ast.AssignExpression:
+		identifier, ok := n.Left.(*ast.Identifier)
+		if !ok || n.Operator != token.ASSIGN {
+			return
+		}
+		if variable := variableKey(metadata, identifier.Name); variable != nil {
+			g.assign(*variable, n.Right, metadata)
+		}
+	case *ast.CallExpression:
+		g.addSite(program, n, n.Callee, metadata)
+	case *ast.NewExpression:
+		g.addSite(program, n, n.Callee, metadata)
+	}
+}
+
+// variableKey returns the key of the variable from the current node, nil if it is resolved in a dynamic scope
+func variableKey(metadata []walker.Metadata, name string) *key {
+	binding := walker.ResolveBinding(metadata, name)
+	if binding.Unknown {
+		return nil
+	}
+	if _, isProgram := binding.Scope.(*ast.Program); isProgram {
+		return &key{name: name}
+	}
+
+	return &key{binding.Scope, name}
+}
+
+// assign records the value of an assignment to the variable, if it is a function or a variable
+func (g *Graph) assign(variable key, expression ast.Expression, metadata []walker.Metadata) {
+	switch e := expression.(type) {
+	case *ast.FunctionLiteral:
+		g.values[variable] = append(g.values[variable], value{function: e})
+	case *ast.Identifier:
+		if alias := variableKey(metadata, e.Name); alias != nil && *alias != variable {
+			g.values[variable] = append(g.values[variable], value{alias: alias})
+		}
+	}
+}
+
+func (g *Graph) addSite(program *ast.Program, call, callee ast.Expression, metadata []walker.Metadata) {
+	caller := g.programs[program]
+	if function := walker.FindParentFunction(metadata); function != nil {
+		caller = g.functions[function]
+	}
+
+	s := site{caller: caller, call: call, callee: callee}
+	if identifier, ok := callee.(*ast.Identifier); ok {
+		s.variable = variableKey(metadata, identifier.Name)
+		s.unknown = s.variable == nil
+	}
+	g.sites = append(g.sites, s)
+}
+
+// resolve adds the edges of the call sites
+func (g *Graph) resolve() {
+	for _, s := range g.sites {
+		var functions []*ast.FunctionLiteral
+		switch {
+		case s.variable != nil:
+			functions = g.functionsOf(*s.variable, map[key]bool{})
+		case !s.unknown:
+			if function, ok := s.callee.(*ast.FunctionLiteral); ok {
+				functions = append(functions, function)
+			}
+		}
+
+		if len(functions) == 0 {
+			g.Unresolved = append(g.Unresolved, s.call)
+			continue
+		}
+		for _, function := range functions {
+			g.addEdge(s.caller, g.functions[function], s.call)
+		}
+	}
+}
+
+// functionsOf returns the functions the variable may hold
+func (g *Graph) functionsOf(variable key, visited map[key]bool) []*ast.FunctionLiteral {
+	if visited[variable] {
+		return nil
+	}
+	visited[variable] = true
+
+	var functions []*ast.FunctionLiteral
+	for _, v := range g.values[variable] {
+		if v.function != nil {
+			functions = append(functions, v.function)
+		} else {
+			functions = append(functions, g.functionsOf(*v.alias, visited)...)
+		}
+	}
+
+	return functions
+}
+
+func (g *Graph) addEdge(caller, callee *Node, call ast.Expression) {
+	for _, edge := range caller.Callees {
+		if edge.Callee == callee && edge.Site == call {
+			return
+		}
+	}
+
+	edge := &Edge{Caller: caller, Callee: callee, Site: call}
+	caller.Callees = append(caller.Callees, edge)
+	callee.Callers = append(callee.Callers, edge)
+	g.Edges = append(g.Edges, edge)
+}
+
+// Node returns the node of the function, nil if not found
+func (g *Graph) Node(function *ast.FunctionLiteral) *Node {
+	return g.functions[function]
+}
+
+// Program returns the node of the top level code of the program, nil if not found
+func (g *Graph) Program(program *ast.Program) *Node {
+	return g.programs[program]
+}
+
+// Position returns the position of the index in the file of the node, nil if unknown
+func (n *Node) Position(idx file.Idx) *file.Position {
+	if n.Program == nil || n.Program.File == nil {
+		return nil
+	}
+
+	return n.Program.File.Position(idx)
+}
+
+// DOT returns the graph in the DOT format of graphviz, with an edge per caller and callee
+func (g *Graph) DOT() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph callgraph {\n")
+	for _, node := range g.Nodes {
+		shape := "ellipse"
+		if node.Function == nil {
+			shape = "box"
+		}
+		fmt.Fprintf(&buffer, "\tn%d [label=%q, shape=%s];\n", node.Index, node.Name, shape)
+	}
+
+	connected := map[[2]int]bool{}
+	for _, edge := range g.Edges {
+		pair := [2]int{edge.Caller.Index, edge.Callee.Index}
+		if connected[pair] {
+			continue
+		}
+		connected[pair] = true
+		fmt.Fprintf(&buffer, "\tn%d -> n%d;\n", pair[0], pair[1])
+	}
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+type jsonNode struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+type jsonEdge struct {
+	Caller int  `json:"caller"`
+	Callee int  `json:"callee"`
+	New    bool `json:"new,omitempty"`
+	Line   int  `json:"line,omitempty"`
+	Column int  `json:"column,omitempty"`
+}
+
+// JSON returns the nodes and edges of the graph in JSON
+func (g *Graph) JSON() ([]byte, error) {
+	result := struct {
+		Nodes []jsonNode `json:"nodes"`
+		Edges []jsonEdge `json:"edges"`
+	}{
+		Nodes: []jsonNode{},
+		Edges: []jsonEdge{},
+	}
+
+	for _, node := range g.Nodes {
+		n := jsonNode{ID: node.Index, Name: node.Name}
+		if node.Program.File != nil {
+			n.File = node.Program.File.Name()
+		}
+		if node.Function != nil {
+			if position := node.Position(node.Function.Idx0()); position != nil {
+				n.Line, n.Column = position.Line, position.Column
+			}
+		}
+		result.Nodes = append(result.Nodes, n)
+	}
+
+	for _, edge := range g.Edges {
+		_, isNew := edge.Site.(*ast.NewExpression)
+		e := jsonEdge{Caller: edge.Caller.Index, Callee: edge.Callee.Index, New: isNew}
+		if position := edge.Caller.Position(edge.Site.Idx0()); position != nil {
+			e.Line, e.Column = position.Line, position.Column
+		}
+		result.Edges = append(result.Edges, e)
+	}
+
+	return json.Marshal(result)
+}
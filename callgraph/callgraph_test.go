@@ -0,0 +1,100 @@
+package callgraph
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// calls describes the edges of the graph by the names of the nodes, sorted
+func calls(g *Graph) string {
+	var result []string
+	for _, edge := range g.Edges {
+		result = append(result, edge.Caller.Name+"->"+edge.Callee.Name)
+	}
+	sort.Strings(result)
+
+	return strings.Join(result, ", ")
+}
+
+func parse(t *testing.T, name, src string) *ast.Program {
+	program, err := parser.ParseFile(nil, name, src, 0)
+	if err != nil {
+		t.Fatalf("Failed, %v", err)
+	}
+
+	return program
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		src        string
+		expected   string
+		unresolved int
+	}{
+		{`function a() { b() } function b() {} a()`, "a->b, program->a", 0},
+		{`function a() {} var f = a; var g = f; g()`, "program->a", 0},
+		{`var f = function a() { a() }; new f()`, "a->a, program->a", 0},
+		{`function a() {} function b() { var a = function c() {}; a() }`, "b->c", 0},
+		{`(function a() {})()`, "program->a", 0},
+		{`var f = function a() {}; f = function b() {}; f()`, "program->a, program->b", 0},
+		{`o.m(); x()`, "", 2},
+		{`function a() {} function b() { with (o) { a() } }`, "", 1},
+	}
+
+	for i, test := range tests {
+		g := Build(parse(t, "", test.src))
+		if result := calls(g); result != test.expected {
+			t.Errorf("[%v] Failed, %q != %q", i, test.expected, result)
+		}
+		if len(g.Unresolved) != test.unresolved {
+			t.Errorf("[%v] Failed, number of unresolved calls not correct, %v != %v", i, test.unresolved, len(g.Unresolved))
+		}
+	}
+}
+
+func TestMultipleFiles(t *testing.T) {
+	a := parse(t, "a.js", `function util() {} main()`)
+	b := parse(t, "b.js", "var main = function start() {\n  util()\n}")
+
+	g := Build(a, b)
+	if result, expected := calls(g), "a.js->start, start->util"; result != expected {
+		t.Errorf("Failed, %q != %q", expected, result)
+	}
+
+	data, err := g.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Nodes []struct {
+			Name string
+			File string
+			Line int
+		}
+		Edges []struct {
+			Caller, Callee, Line int
+		}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Nodes) != 4 || len(decoded.Edges) != 2 {
+		t.Fatalf("Failed, unexpected JSON %s", data)
+	}
+	if node := decoded.Nodes[decoded.Edges[1].Callee]; node.Name != "util" || node.File != "a.js" || decoded.Edges[1].Line != 2 {
+		t.Errorf("Failed, unexpected JSON %s", data)
+	}
+
+	dot := g.DOT()
+	for _, expected := range []string{"digraph callgraph {", `n0 [label="a.js", shape=box];`, "n3 -> n1;"} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("Failed, %v not found in %v", expected, dot)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package cfg
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+)
+
+// Build returns the control flow graphs of the given program or function, and of every function inside it.
+// The graph of the given node is the first.
+func Build(node ast.Node) []*Graph {
+	b := &builder{}
+	if program, ok := node.(*ast.Program); ok {
+		b.file = program.File
+	}
+
+	walker.NewWalker(b).Begin(node)
+	return b.graphs
+}
+
+// builder is a visitor building the graphs while walking
+type builder struct {
+	walker.VisitorImpl
+	file      *file.File
+	graphs    []*Graph
+	functions []*function
+}
+
+// function is the state of the graph being built for a program or a function
+type function struct {
+	graph    *Graph
+	current  *Block
+	handlers []*handler
+	targets  map[ast.Statement]*target
+}
+
+// handler is an enclosing try statement
+type handler struct {
+	catch   *Block
+	finally *Block
+	// inCatch is true while building the catch clause
+	inCatch bool
+	// exits are the jumps continuing after the finally block
+	exits []exit
+	// rethrow is true if the finally block can be entered by an exception
+	rethrow bool
+}
+
+// target is the destination of break and continue statements of a statement
+type target struct {
+	breakTo    *Block
+	continueTo *Block
+	// handlers is the number of handlers enclosing the statement
+	handlers int
+}
+
+type exit struct {
+	to       *Block
+	handlers int
+}
+
+func (b *builder) begin(node ast.Node) {
+	graph := &Graph{
+		Node: node,
+		File: b.file,
+	}
+	graph.Entry = graph.newBlock()
+	graph.Exit = graph.newBlock()
+
+	f := &function{
+		graph:   graph,
+		targets: map[ast.Statement]*target{},
+	}
+	f.current = graph.newBlock()
+	graph.addEdge(graph.Entry, f.current, Normal)
+
+	b.graphs = append(b.graphs, graph)
+	b.functions = append(b.functions, f)
+}
+
+func (b *builder) end() {
+	f := b.function()
+	f.graph.addEdge(f.current, f.graph.Exit, Normal)
+	b.functions = b.functions[:len(b.functions)-1]
+}
+
+func (b *builder) function() *function {
+	return b.functions[len(b.functions)-1]
+}
+
+// newBlock adds a block, connected to the enclosing exception handler
+func (f *function) newBlock() *Block {
+	block := f.graph.newBlock()
+	f.graph.addEdge(block, f.handler(), Exception)
+	return block
+}
+
+// handler returns the block handling exceptions thrown at this point, nil if uncaught
+func (f *function) handler() *Block {
+	for i := len(f.handlers) - 1; i >= 0; i-- {
+		h := f.handlers[i]
+		if !h.inCatch && h.catch != nil {
+			return h.catch
+		}
+		if h.finally != nil {
+			h.rethrow = true
+			return h.finally
+		}
+	}
+
+	return nil
+}
+
+// add appends the node to the current block, unreachable nodes are added to a new block
+func (f *function) add(node ast.Node) {
+	if f.current == nil {
+		f.current = f.newBlock()
+	}
+	f.current.Nodes = append(f.current.Nodes, node)
+}
+
+// jump connects the block to the destination, through the finally blocks of the handlers in between
+func (f *function) jump(from, to *Block, handlers int) {
+	for i := len(f.handlers) - 1; i >= handlers; i-- {
+		h := f.handlers[i]
+		if h.finally != nil {
+			f.graph.addEdge(from, h.finally, Normal)
+			h.exits = append(h.exits, exit{to: to, handlers: handlers})
+			return
+		}
+	}
+
+	f.graph.addEdge(from, to, Normal)
+}
+
+// join continues from a new block following the given blocks, if any of them are reachable
+func (f *function) join(blocks ...*Block) {
+	f.current = nil
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+		if f.current == nil {
+			f.current = f.newBlock()
+		}
+		f.graph.addEdge(block, f.current, Normal)
+	}
+}
+
+// finish continues from the block following a statement, removing it if it is not reachable
+func (f *function) finish(after *Block) {
+	if len(after.Preds) == 0 {
+		f.graph.removeBlock(after)
+		f.current = nil
+		return
+	}
+
+	f.current = after
+}
+
+func (b *builder) VisitProgram(w *walker.Walker, node *ast.Program, metadata []walker.Metadata) walker.Metadata {
+	b.begin(node)
+	b.VisitorImpl.VisitProgram(w, node, metadata)
+	b.end()
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitFunction(w *walker.Walker, node *ast.FunctionLiteral, metadata []walker.Metadata) walker.Metadata {
+	b.begin(node)
+	b.VisitorImpl.VisitFunction(w, node, metadata)
+	b.end()
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitExpression(w *walker.Walker, node *ast.ExpressionStatement, metadata []walker.Metadata) walker.Metadata {
+	b.function().add(node)
+	return b.VisitorImpl.VisitExpression(w, node, metadata)
+}
+
+func (b *builder) VisitVariableStatement(w *walker.Walker, node *ast.VariableStatement, metadata []walker.Metadata) walker.Metadata {
+	b.function().add(node)
+	return b.VisitorImpl.VisitVariableStatement(w, node, metadata)
+}
+
+func (b *builder) VisitDebugger(w *walker.Walker, node *ast.DebuggerStatement, metadata []walker.Metadata) walker.Metadata {
+	b.function().add(node)
+	return b.VisitorImpl.VisitDebugger(w, node, metadata)
+}
+
+func (b *builder) VisitReturn(w *walker.Walker, node *ast.ReturnStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node)
+	b.VisitorImpl.VisitReturn(w, node, metadata)
+	f.jump(f.current, f.graph.Exit, 0)
+	f.current = nil
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitThrow(w *walker.Walker, node *ast.ThrowStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node)
+	b.VisitorImpl.VisitThrow(w, node, metadata)
+	if handler := f.handler(); handler != nil {
+		f.graph.addEdge(f.current, handler, Exception)
+	} else {
+		f.graph.addEdge(f.current, f.graph.Exit, Exception)
+	}
+	f.current = nil
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitBranch(w *walker.Walker, node *ast.BranchStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node)
+	b.VisitorImpl.VisitBranch(w, node, metadata)
+
+	if t, ok := f.targets[walker.CurrentMetadata(metadata).Target()]; ok {
+		to := t.breakTo
+		if node.Token == token.CONTINUE {
+			to = t.continueTo
+		}
+		f.jump(f.current, to, t.handlers)
+	}
+	f.current = nil
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitIf(w *walker.Walker, node *ast.IfStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node.Test)
+	w.Walk(node.Test, metadata)
+	test := f.current
+
+	f.current = f.newBlock()
+	f.graph.addEdge(test, f.current, True)
+	w.Walk(node.Consequent, metadata)
+	consequent := f.current
+
+	if node.Alternate == nil {
+		f.current = f.newBlock()
+		f.graph.addEdge(consequent, f.current, Normal)
+		f.graph.addEdge(test, f.current, False)
+		return walker.CurrentMetadata(metadata)
+	}
+
+	f.current = f.newBlock()
+	f.graph.addEdge(test, f.current, False)
+	w.Walk(node.Alternate, metadata)
+	f.join(consequent, f.current)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitWhile(w *walker.Walker, node *ast.WhileStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	head := f.newBlock()
+	f.graph.addEdge(f.current, head, Normal)
+	after := f.newBlock()
+	f.targets[node] = &target{breakTo: after, continueTo: head, handlers: len(f.handlers)}
+
+	f.current = head
+	f.add(node.Test)
+	w.Walk(node.Test, metadata)
+
+	f.current = f.newBlock()
+	f.graph.addEdge(head, f.current, True)
+	f.graph.addEdge(head, after, False)
+	w.Walk(node.Body, metadata)
+	f.graph.addEdge(f.current, head, Normal)
+	f.finish(after)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitDoWhile(w *walker.Walker, node *ast.DoWhileStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	body := f.newBlock()
+	f.graph.addEdge(f.current, body, Normal)
+	test := f.newBlock()
+	after := f.newBlock()
+	f.targets[node] = &target{breakTo: after, continueTo: test, handlers: len(f.handlers)}
+
+	f.current = body
+	w.Walk(node.Body, metadata)
+	f.graph.addEdge(f.current, test, Normal)
+
+	f.current = test
+	f.add(node.Test)
+	w.Walk(node.Test, metadata)
+	f.graph.addEdge(test, body, True)
+	f.graph.addEdge(test, after, False)
+	f.finish(after)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitFor(w *walker.Walker, node *ast.ForStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	// The parser uses an empty sequence for a missing initializer
+	if sequence, ok := node.Initializer.(*ast.SequenceExpression); node.Initializer != nil && (!ok || len(sequence.Sequence) > 0) {
+		f.add(node.Initializer)
+		w.Walk(node.Initializer, metadata)
+	}
+
+	head := f.newBlock()
+	f.graph.addEdge(f.current, head, Normal)
+	update := head
+	if node.Update != nil {
+		update = f.newBlock()
+	}
+	after := f.newBlock()
+	f.targets[node] = &target{breakTo: after, continueTo: update, handlers: len(f.handlers)}
+
+	f.current = head
+	kind := Normal
+	if node.Test != nil {
+		f.add(node.Test)
+		w.Walk(node.Test, metadata)
+		f.graph.addEdge(head, after, False)
+		kind = True
+	}
+
+	f.current = f.newBlock()
+	f.graph.addEdge(head, f.current, kind)
+	w.Walk(node.Body, metadata)
+
+	if node.Update != nil {
+		f.graph.addEdge(f.current, update, Normal)
+		f.current = update
+		f.add(node.Update)
+		w.Walk(node.Update, metadata)
+	}
+	f.graph.addEdge(f.current, head, Normal)
+	f.finish(after)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitForIn(w *walker.Walker, node *ast.ForInStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node.Source)
+	w.Walk(node.Source, metadata)
+
+	head := f.newBlock()
+	f.graph.addEdge(f.current, head, Normal)
+	after := f.newBlock()
+	f.targets[node] = &target{breakTo: after, continueTo: head, handlers: len(f.handlers)}
+
+	f.current = head
+	f.add(node.Into)
+	w.Walk(node.Into, metadata)
+
+	f.current = f.newBlock()
+	f.graph.addEdge(head, f.current, True)
+	f.graph.addEdge(head, after, False)
+	w.Walk(node.Body, metadata)
+	f.graph.addEdge(f.current, head, Normal)
+	f.finish(after)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitSwitch(w *walker.Walker, node *ast.SwitchStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	f.add(node.Discriminant)
+	w.Walk(node.Discriminant, metadata)
+	for _, c := range node.Body {
+		if c.Test != nil {
+			f.add(c.Test)
+		}
+	}
+	discriminant := f.current
+
+	after := f.newBlock()
+	f.targets[node] = &target{breakTo: after, handlers: len(f.handlers)}
+
+	hasDefault := false
+	var previous *Block
+	for _, c := range node.Body {
+		f.current = f.newBlock()
+		f.graph.addEdge(discriminant, f.current, Normal)
+		// Falling through from the previous case
+		f.graph.addEdge(previous, f.current, Normal)
+		w.Walk(c, metadata)
+		previous = f.current
+
+		if c.Test == nil {
+			hasDefault = true
+		}
+	}
+
+	f.graph.addEdge(previous, after, Normal)
+	if !hasDefault {
+		f.graph.addEdge(discriminant, after, Normal)
+	}
+	f.finish(after)
+
+	return walker.CurrentMetadata(metadata)
+}
+
+func (b *builder) VisitLabelled(w *walker.Walker, node *ast.LabelledStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+
+	// Loops and switches handle their own breaks
+	var after *Block
+	statement := labelledTarget(node)
+	if _, isSwitch := statement.(*ast.SwitchStatement); !isSwitch && !walker.IsLoop(statement) {
+		after = f.newBlock()
+		f.targets[statement] = &target{breakTo: after, handlers: len(f.handlers)}
+	}
+
+	b.VisitorImpl.VisitLabelled(w, node, metadata)
+
+	if after != nil {
+		f.graph.addEdge(f.current, after, Normal)
+		f.finish(after)
+	}
+
+	return walker.CurrentMetadata(metadata)
+}
+
+// labelledTarget returns the statement of the labelled statement, skipping nested labels
+func labelledTarget(labelled *ast.LabelledStatement) ast.Statement {
+	statement := labelled.Statement
+	for {
+		nested, ok := statement.(*ast.LabelledStatement)
+		if !ok {
+			return statement
+		}
+		statement = nested.Statement
+	}
+}
+
+func (b *builder) VisitTry(w *walker.Walker, node *ast.TryStatement, metadata []walker.Metadata) walker.Metadata {
+	f := b.function()
+	outer := f.handler()
+
+	h := &handler{}
+	if node.Catch != nil {
+		h.catch = f.graph.newBlock()
+	}
+	if node.Finally != nil {
+		h.finally = f.graph.newBlock()
+		f.graph.addEdge(h.finally, outer, Exception)
+	}
+	if h.catch != nil {
+		if h.finally != nil {
+			f.graph.addEdge(h.catch, h.finally, Exception)
+			h.rethrow = true
+		} else {
+			f.graph.addEdge(h.catch, outer, Exception)
+		}
+	}
+
+	f.handlers = append(f.handlers, h)
+	body := f.newBlock()
+	f.graph.addEdge(f.current, body, Normal)
+	f.current = body
+	w.Walk(node.Body, metadata)
+	try := f.current
+
+	var catch *Block
+	if node.Catch != nil {
+		h.inCatch = true
+		f.current = h.catch
+		f.add(node.Catch)
+		w.Walk(node.Catch, metadata)
+		catch = f.current
+	}
+	f.handlers = f.handlers[:len(f.handlers)-1]
+
+	if h.finally == nil {
+		f.join(try, catch)
+		return walker.CurrentMetadata(metadata)
+	}
+
+	f.graph.addEdge(try, h.finally, Normal)
+	f.graph.addEdge(catch, h.finally, Normal)
+	f.current = h.finally
+	w.Walk(node.Finally, metadata)
+	if f.current == nil {
+		return walker.CurrentMetadata(metadata)
+	}
+
+	finally := f.current
+	for _, e := range h.exits {
+		f.jump(finally, e.to, e.handlers)
+	}
+	if h.rethrow {
+		if outer != nil {
+			f.graph.addEdge(finally, outer, Exception)
+		} else {
+			f.graph.addEdge(finally, f.graph.Exit, Exception)
+		}
+	}
+
+	f.current = nil
+	if try != nil || catch != nil {
+		f.join(finally)
+	}
+
+	return walker.CurrentMetadata(metadata)
+}
@@ -0,0 +1,233 @@
+// Package cfg builds control flow graphs of programs and functions walked by the walker.
+package cfg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// EdgeKind describes when control flows along an edge
+type EdgeKind int
+
+const (
+	Normal EdgeKind = iota
+	True
+	False
+	Exception
+)
+
+// String returns the name of the kind
+func (k EdgeKind) String() string {
+	switch k {
+	case True:
+		return "true"
+	case False:
+		return "false"
+	case Exception:
+		return "exception"
+	default:
+		return ""
+	}
+}
+
+// Edge connects two blocks
+type Edge struct {
+	From *Block
+	To   *Block
+	Kind EdgeKind
+}
+
+// Block is a basic block, a sequence of nodes executed in order.
+// The nodes are simple statements, the tests of control statements and catch clauses,
+// which only bind their parameter.
+type Block struct {
+	Index int
+	Nodes []ast.Node
+	Succs []*Edge
+	Preds []*Edge
+}
+
+// Graph is the control flow graph of a program or a function
+type Graph struct {
+	// Node is the *ast.Program or *ast.FunctionLiteral of the graph
+	Node   ast.Node
+	Entry  *Block
+	Exit   *Block
+	Blocks []*Block
+	File   *file.File
+}
+
+// newBlock adds a new empty block to the graph
+func (g *Graph) newBlock() *Block {
+	block := &Block{Index: len(g.Blocks)}
+	g.Blocks = append(g.Blocks, block)
+	return block
+}
+
+// removeBlock removes a block without predecessors from the graph
+func (g *Graph) removeBlock(block *Block) {
+	for _, edge := range block.Succs {
+		for i, pred := range edge.To.Preds {
+			if pred == edge {
+				edge.To.Preds = append(edge.To.Preds[:i], edge.To.Preds[i+1:]...)
+				break
+			}
+		}
+	}
+
+	g.Blocks = append(g.Blocks[:block.Index], g.Blocks[block.Index+1:]...)
+	for i, b := range g.Blocks {
+		b.Index = i
+	}
+}
+
+// addEdge connects the blocks, unless they are already connected by the same kind of edge
+func (g *Graph) addEdge(from, to *Block, kind EdgeKind) {
+	if from == nil || to == nil {
+		return
+	}
+	for _, edge := range from.Succs {
+		if edge.To == to && edge.Kind == kind {
+			return
+		}
+	}
+
+	edge := &Edge{From: from, To: to, Kind: kind}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+}
+
+// Block returns the block containing the node, nil if none
+func (g *Graph) Block(node ast.Node) *Block {
+	for _, block := range g.Blocks {
+		for _, n := range block.Nodes {
+			if n == node {
+				return block
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reachable returns the blocks reachable from the entry
+func (g *Graph) Reachable() map[*Block]bool {
+	reachable := map[*Block]bool{}
+	queue := []*Block{g.Entry}
+	for len(queue) > 0 {
+		block := queue[0]
+		queue = queue[1:]
+		if reachable[block] {
+			continue
+		}
+
+		reachable[block] = true
+		for _, edge := range block.Succs {
+			queue = append(queue, edge.To)
+		}
+	}
+
+	return reachable
+}
+
+// Name returns the name of the graph
+func (g *Graph) Name() string {
+	switch n := g.Node.(type) {
+	case *ast.Program:
+		return "program"
+	case *ast.FunctionLiteral:
+		if n.Name != nil {
+			return n.Name.Name
+		}
+		return fmt.Sprintf("function@%v", n.Idx0())
+	}
+
+	return "graph"
+}
+
+// DOT returns the graph in the DOT format of graphviz
+func (g *Graph) DOT() string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "digraph %q {\n", g.Name())
+	buffer.WriteString("\tnode [shape=box];\n")
+	g.writeDOT(&buffer, "b")
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+// DOT returns the graphs as clusters of a single graph in the DOT format of graphviz
+func DOT(graphs []*Graph) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph cfg {\n")
+	buffer.WriteString("\tnode [shape=box];\n")
+	for i, graph := range graphs {
+		fmt.Fprintf(&buffer, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&buffer, "\tlabel=%q;\n", graph.Name())
+		graph.writeDOT(&buffer, fmt.Sprintf("g%d_b", i))
+		buffer.WriteString("\t}\n")
+	}
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+// writeDOT writes the blocks and edges, naming the blocks by the prefix and their index
+func (g *Graph) writeDOT(buffer *bytes.Buffer, prefix string) {
+	for _, block := range g.Blocks {
+		var label string
+		switch block {
+		case g.Entry:
+			label = "entry"
+		case g.Exit:
+			label = "exit"
+		default:
+			for _, node := range block.Nodes {
+				label += g.label(node) + "\\l"
+			}
+		}
+		fmt.Fprintf(buffer, "\t%s%d [label=\"%s\"];\n", prefix, block.Index, label)
+	}
+
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			fmt.Fprintf(buffer, "\t%s%d -> %s%d", prefix, edge.From.Index, prefix, edge.To.Index)
+			if edge.Kind != Normal {
+				fmt.Fprintf(buffer, " [label=%q]", edge.Kind.String())
+			}
+			buffer.WriteString(";\n")
+		}
+	}
+}
+
+// label returns the first line of the source of the node, or its type if the source is unknown
+func (g *Graph) label(node ast.Node) string {
+	text := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	if g.File != nil {
+		start, end := int(node.Idx0())-g.File.Base(), int(node.Idx1())-g.File.Base()
+		source := g.File.Source()
+		if start >= 0 && start < len(source) {
+			line := source[start:]
+			if end > start && end <= len(source) {
+				line = source[start:end]
+			}
+			if i := strings.IndexAny(line, "\n{};"); i >= 0 {
+				line = line[:i]
+			}
+			line = strings.TrimSpace(line)
+			if len(line) > 40 {
+				line = line[:40] + "..."
+			}
+			if line != "" {
+				text = line
+			}
+		}
+	}
+
+	text = strings.Replace(text, "\\", "\\\\", -1)
+	return strings.Replace(text, "\"", "\\\"", -1)
+}
@@ -0,0 +1,136 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+)
+
+// edges describes the edges of the graph by the first node of the blocks
+func edges(g *Graph) map[string]bool {
+	name := func(block *Block) string {
+		switch {
+		case block == g.Entry:
+			return "entry"
+		case block == g.Exit:
+			return "exit"
+		case len(block.Nodes) == 0:
+			return "-"
+		}
+		return g.label(block.Nodes[0])
+	}
+
+	result := map[string]bool{}
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			description := fmt.Sprintf("%v -> %v", name(edge.From), name(edge.To))
+			if edge.Kind != Normal {
+				description += " " + edge.Kind.String()
+			}
+			result[description] = true
+		}
+	}
+
+	return result
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		src     string
+		graph   int
+		present []string
+		absent  []string
+	}{
+		{`a(); if (b) { c() } else { d() } e()`, 0,
+			[]string{"entry -> a()", "a() -> c() true", "a() -> d() false", "c() -> e()", "d() -> e()", "e() -> exit"}, nil},
+		{`function f() { if (a) { return } b() }`, 1,
+			[]string{"a -> return true", "a -> b() false", "return -> exit"}, []string{"return -> b()"}},
+		{`while (a) { if (b) break; c() } d()`, 0,
+			[]string{"a -> b true", "a -> d() false", "b -> break true", "break -> d()", "c() -> a"}, nil},
+		{`do { a() } while (b); c()`, 0,
+			[]string{"a() -> b", "b -> a() true", "b -> c() false"}, nil},
+		{`for (i = 0; i < 3; i = i + 1) { if (a) continue; b() }`, 0,
+			[]string{"i = 0 -> i < 3", "i < 3 -> a true", "continue -> i = i + 1", "b() -> i = i + 1", "i = i + 1 -> i < 3", "i < 3 -> - false", "- -> exit"}, nil},
+		{`for (k in o) { a() }`, 0,
+			[]string{"o -> k", "k -> a() true", "a() -> k", "k -> - false"}, nil},
+		{`switch (a) { case 1: b(); case 2: c(); break; default: d() }`, 0,
+			[]string{"a -> b()", "a -> c()", "a -> d()", "b() -> c()", "c() -> -", "d() -> -"}, []string{"a -> -"}},
+		{`switch (a) { case 1: b() }`, 0,
+			[]string{"a -> b()", "a -> -"}, nil},
+		{`a: for (;;) { for (;;) { continue a } }`, 0,
+			[]string{"continue a -> -"}, []string{"- -> exit"}},
+		{`a: { if (b) break a; c() } d()`, 0,
+			[]string{"break a -> d()", "c() -> d()"}, nil},
+		{`try { a() } catch (e) { b() } c()`, 0,
+			[]string{"a() -> catch (e) exception", "a() -> c()", "catch (e) -> c()"}, []string{"catch (e) -> exit exception"}},
+		{`try { throw a } finally { b() } c()`, 0,
+			[]string{"ThrowStatement -> b() exception", "b() -> exit exception"}, []string{"b() -> c()"}},
+		{`function f() { try { return 1 } finally { a() } b() }`, 1,
+			[]string{"return 1 -> a()", "a() -> exit"}, []string{"return 1 -> exit"}},
+		{`while (a) { try { break } finally { b() } }`, 0,
+			[]string{"break -> b()", "b() -> -", "- -> exit"}, nil},
+		{`function f() { throw a; b() }`, 1,
+			[]string{"ThrowStatement -> exit exception"}, []string{"ThrowStatement -> b()"}},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		graphs := Build(program)
+		result := edges(graphs[test.graph])
+		for _, edge := range test.present {
+			if !result[edge] {
+				t.Errorf("[%v] Failed, missing edge %v in %v", i, edge, result)
+			}
+		}
+		for _, edge := range test.absent {
+			if result[edge] {
+				t.Errorf("[%v] Failed, unexpected edge %v", i, edge)
+			}
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", `function f() { return; a() } b()`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graphs := Build(program)
+	if len(graphs) != 2 {
+		t.Fatalf("Failed, number of graphs not correct, 2 != %v", len(graphs))
+	}
+
+	reachable := graphs[1].Reachable()
+	for _, block := range graphs[1].Blocks {
+		unreachable := len(block.Nodes) > 0 && graphs[1].label(block.Nodes[0]) == "a()"
+		if unreachable == reachable[block] {
+			t.Errorf("Failed, reachability of block %v not correct", block.Index)
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", `if (a) { b("x") }`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dot := Build(program)[0].DOT()
+	for _, expected := range []string{`digraph "program" {`, `[label="b(\"x\")\l"]`, `[label="true"]`, `b0 [label="entry"]`} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("Failed, %v not found in %v", expected, dot)
+		}
+	}
+
+	if dot := DOT(Build(program)); !strings.Contains(dot, "subgraph cluster_0 {") {
+		t.Errorf("Failed, cluster not found in %v", dot)
+	}
+}
@@ -0,0 +1,136 @@
+package walker
+
+import (
+	"github.com/robertkrimen/otto/ast"
+)
+
+// Children returns the child nodes of the given node, in the order they are walked by VisitorImpl
+func Children(node ast.Node) []ast.Node {
+	var children []ast.Node
+	add := func(nodes ...ast.Node) {
+		for _, n := range nodes {
+			if !isNil(n) {
+				children = append(children, n)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.Program:
+		for _, e := range n.Body {
+			add(e)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range n.Value {
+			add(e)
+		}
+	case *ast.AssignExpression:
+		add(n.Left, n.Right)
+	case *ast.BinaryExpression:
+		add(n.Left, n.Right)
+	case *ast.BlockStatement:
+		for _, e := range n.List {
+			add(e)
+		}
+	case *ast.BracketExpression:
+		add(n.Left, n.Member)
+	case *ast.BranchStatement:
+		add(n.Label)
+	case *ast.CallExpression:
+		add(n.Callee)
+		for _, e := range n.ArgumentList {
+			add(e)
+		}
+	case *ast.CaseStatement:
+		add(n.Test)
+		for _, e := range n.Consequent {
+			add(e)
+		}
+	case *ast.CatchStatement:
+		add(n.Parameter, n.Body)
+	case *ast.ConditionalExpression:
+		add(n.Test, n.Consequent, n.Alternate)
+	case *ast.DotExpression:
+		add(n.Left, n.Identifier)
+	case *ast.DoWhileStatement:
+		add(n.Test, n.Body)
+	case *ast.ExpressionStatement:
+		add(n.Expression)
+	case *ast.ForInStatement:
+		add(n.Into, n.Source, n.Body)
+	case *ast.ForStatement:
+		add(n.Initializer, n.Test, n.Update, n.Body)
+	case *ast.FunctionLiteral:
+		add(n.Name)
+		if n.ParameterList != nil {
+			for _, e := range n.ParameterList.List {
+				add(e)
+			}
+		}
+		add(n.Body)
+	case *ast.FunctionStatement:
+		add(n.Function)
+	case *ast.IfStatement:
+		add(n.Test, n.Consequent, n.Alternate)
+	case *ast.LabelledStatement:
+		add(n.Label, n.Statement)
+	case *ast.NewExpression:
+		add(n.Callee)
+		for _, e := range n.ArgumentList {
+			add(e)
+		}
+	case *ast.ObjectLiteral:
+		for _, p := range n.Value {
+			add(p.Value)
+		}
+	case *ast.ReturnStatement:
+		add(n.Argument)
+	case *ast.SequenceExpression:
+		for _, e := range n.Sequence {
+			add(e)
+		}
+	case *ast.SwitchStatement:
+		add(n.Discriminant)
+		for _, e := range n.Body {
+			add(e)
+		}
+	case *ast.ThrowStatement:
+		add(n.Argument)
+	case *ast.TryStatement:
+		add(n.Body, n.Catch, n.Finally)
+	case *ast.UnaryExpression:
+		add(n.Operand)
+	case *ast.VariableExpression:
+		add(n.Initializer)
+	case *ast.VariableStatement:
+		for _, e := range n.List {
+			add(e)
+		}
+	case *ast.WhileStatement:
+		add(n.Test, n.Body)
+	case *ast.WithStatement:
+		add(n.Object, n.Body)
+	}
+
+	return children
+}
+
+// isNil returns true if the node is nil, including typed nil pointers
+func isNil(node ast.Node) bool {
+	if node == nil {
+		return true
+	}
+
+	switch n := node.(type) {
+	case *ast.Identifier:
+		return n == nil
+	case *ast.CatchStatement:
+		return n == nil
+	case *ast.FunctionLiteral:
+		return n == nil
+	case *ast.CaseStatement:
+		return n == nil
+	}
+
+	return false
+}
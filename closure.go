@@ -0,0 +1,212 @@
+package walker
+
+import (
+	"sort"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+const ClosureField = "closure"
+
+// Capture describes an outer-scope variable used by a function
+type Capture struct {
+	Name string
+	// Idx is the index of the declaration, -1 if the variable is an undeclared global,
+	// Unknown if the variable is resolved in a dynamic scope
+	Idx file.Idx
+	// Scope is the node declaring the variable, nil if the variable is an undeclared global,
+	// or the dynamic scope resolving the variable
+	Scope ast.Node
+	Read  bool
+	Write bool
+	// Loop is the enclosing loop iterating the variable, if any
+	Loop ast.Statement
+}
+
+// Closure summarizes the free variables of a function
+type Closure struct {
+	Function *ast.FunctionLiteral
+	Captures map[string]*Capture
+}
+
+// NewClosure returns a new instance
+func NewClosure(function *ast.FunctionLiteral) *Closure {
+	return &Closure{
+		Function: function,
+		Captures: map[string]*Capture{},
+	}
+}
+
+// Reads returns the sorted names of the captured variables read by the function
+func (c *Closure) Reads() []string {
+	var names []string
+	for name, capture := range c.Captures {
+		if capture.Read {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Writes returns the sorted names of the captured variables written by the function
+func (c *Closure) Writes() []string {
+	var names []string
+	for name, capture := range c.Captures {
+		if capture.Write {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// LoopCaptures returns the captured variables iterated by an enclosing loop
+func (c *Closure) LoopCaptures() []*Capture {
+	var captures []*Capture
+	for _, capture := range c.Captures {
+		if capture.Loop != nil {
+			captures = append(captures, capture)
+		}
+	}
+	sort.Slice(captures, func(i, j int) bool {
+		return captures[i].Name < captures[j].Name
+	})
+
+	return captures
+}
+
+// Hoistable returns true if the function only captures global variables,
+// meaning it can be moved to the program scope.
+func (c *Closure) Hoistable() bool {
+	for _, capture := range c.Captures {
+		switch capture.Scope.(type) {
+		case nil, *ast.Program:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// ClosureAnalyzer computes the closure of every function literal found by the walker
+type ClosureAnalyzer struct {
+	Closures []*Closure
+}
+
+// NewClosureAnalyzer returns a new instance
+func NewClosureAnalyzer() *ClosureAnalyzer {
+	return &ClosureAnalyzer{}
+}
+
+// Hook returns the hook performing the analysis
+func (a *ClosureAnalyzer) Hook() *Hook {
+	return &Hook{
+		OnNode: a.onNode,
+	}
+}
+
+// Closure returns the closure of the given function, nil if it was not walked
+func (a *ClosureAnalyzer) Closure(function *ast.FunctionLiteral) *Closure {
+	for _, closure := range a.Closures {
+		if closure.Function == function {
+			return closure
+		}
+	}
+
+	return nil
+}
+
+func (a *ClosureAnalyzer) onNode(node ast.Node, metadata []Metadata) error {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		closure := NewClosure(n)
+		CurrentMetadata(metadata)[ClosureField] = closure
+		a.Closures = append(a.Closures, closure)
+	case *ast.Identifier:
+		access := IdentifierAccess(metadata)
+		if access == 0 {
+			return nil
+		}
+
+		idx := file.Idx(-1)
+		var scope ast.Node
+		declaring, unknown := resolveVariable(metadata, n.Name)
+		switch {
+		case unknown:
+			scope = metadata[declaring].Node()
+			idx = Unknown
+		case declaring >= 0:
+			scope = metadata[declaring].Node()
+			idx = metadata[declaring][Vars].(Variables)[n.Name]
+		}
+
+		// Every function between the identifier and the declaring scope captures the variable
+		for i := len(metadata) - 2; i > declaring; i-- {
+			closure, ok := metadata[i][ClosureField].(*Closure)
+			if !ok {
+				continue
+			}
+
+			capture, ok := closure.Captures[n.Name]
+			if !ok {
+				capture = &Capture{
+					Name:  n.Name,
+					Idx:   idx,
+					Scope: scope,
+					Loop:  findLoop(metadata[declaring+1:i], n.Name),
+				}
+				closure.Captures[n.Name] = capture
+			}
+			capture.Read = capture.Read || access&Read != 0
+			capture.Write = capture.Write || access&Write != 0
+		}
+	}
+
+	return nil
+}
+
+// findLoop returns the outermost loop iterating the variable, whose body contains the rest of the metadata
+func findLoop(metadata []Metadata, name string) ast.Statement {
+	for i := 0; i < len(metadata)-1; i++ {
+		child := metadata[i+1].Node()
+		switch n := metadata[i].Node().(type) {
+		case *ast.ForStatement:
+			if n.Body == child && (declaresVariable(n.Initializer, name) || declaresVariable(n.Update, name)) {
+				return n
+			}
+		case *ast.ForInStatement:
+			if n.Body == child && declaresVariable(n.Into, name) {
+				return n
+			}
+		}
+	}
+
+	return nil
+}
+
+// declaresVariable returns true if the expression declares or assigns the variable
+func declaresVariable(expression ast.Expression, name string) bool {
+	switch e := expression.(type) {
+	case *ast.Identifier:
+		return e.Name == name
+	case *ast.VariableExpression:
+		return e.Name == name
+	case *ast.AssignExpression:
+		return declaresVariable(e.Left, name)
+	case *ast.UnaryExpression:
+		return declaresVariable(e.Operand, name)
+	case *ast.SequenceExpression:
+		for _, value := range e.Sequence {
+			if declaresVariable(value, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package walker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestClosureAnalyzer(t *testing.T) {
+	tests := []struct {
+		src       string
+		function  int
+		reads     []string
+		writes    []string
+		loop      []string
+		hoistable bool
+	}{
+		{`var a = 1; function f(b) { return a + b }`, 0, []string{"a"}, nil, nil, true},
+		{`function f() { var a = 1; return function() { a++; return a } }`, 1, []string{"a"}, []string{"a"}, nil, false},
+		{`function f() { var a; function g() { return function() { a = 1 } } }`, 1, nil, []string{"a"}, nil, false},
+		{`function f(a) { return function(a) { return a.b } }`, 1, nil, nil, nil, true},
+		{`function f() { return function g() { return g } }`, 1, nil, nil, nil, true},
+		{`for (var i = 0; i < 3; i++) { setTimeout(function() { log(i) }) }`, 0, []string{"i", "log"}, nil, []string{"i"}, true},
+		{`function f(o) { for (var k in o) { o[k](function() { return k }) } }`, 1, []string{"k"}, nil, []string{"k"}, false},
+		{`function f() { try {} catch (e) { return function() { return e.message } } }`, 1, []string{"e"}, nil, nil, false},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		analyzer := NewClosureAnalyzer()
+		visitor := &VisitorImpl{}
+		visitor.AddHook(analyzer.Hook())
+		NewWalker(visitor).Begin(program)
+
+		closure := analyzer.Closures[test.function]
+		if reads := closure.Reads(); !reflect.DeepEqual(test.reads, reads) {
+			t.Errorf("[%v] Failed, reads not correct, %v != %v", i, test.reads, reads)
+		}
+		if writes := closure.Writes(); !reflect.DeepEqual(test.writes, writes) {
+			t.Errorf("[%v] Failed, writes not correct, %v != %v", i, test.writes, writes)
+		}
+
+		var loop []string
+		for _, capture := range closure.LoopCaptures() {
+			loop = append(loop, capture.Name)
+		}
+		if !reflect.DeepEqual(test.loop, loop) {
+			t.Errorf("[%v] Failed, loop captures not correct, %v != %v", i, test.loop, loop)
+		}
+
+		if test.hoistable != closure.Hoistable() {
+			t.Errorf("[%v] Failed, hoistable not correct, %v != %v", i, test.hoistable, closure.Hoistable())
+		}
+	}
+}
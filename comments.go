@@ -0,0 +1,61 @@
+package walker
+
+import (
+	"sort"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// Comments are the comments attached to a node by the parser, in source order
+type Comments struct {
+	// Leading comments precede the node
+	Leading []*ast.Comment
+	// Trailing comments follow the node
+	Trailing []*ast.Comment
+	// Inner comments are inside the node, e.g. the final comments of a block or the comments after a keyword
+	Inner []*ast.Comment
+}
+
+// All returns the leading, inner and trailing comments
+func (c Comments) All() []*ast.Comment {
+	return append(append(append([]*ast.Comment(nil), c.Leading...), c.Inner...), c.Trailing...)
+}
+
+// Comments returns the comments attached to the node.
+// Comments are only available for programs parsed with parser.StoreComments.
+func (md Metadata) Comments() Comments {
+	var comments Comments
+	list, _ := md[CommentsField].([]*ast.Comment)
+	list = append([]*ast.Comment(nil), list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Begin < list[j].Begin
+	})
+
+	for _, comment := range list {
+		switch comment.Position {
+		case ast.LEADING:
+			comments.Leading = append(comments.Leading, comment)
+		case ast.TRAILING:
+			comments.Trailing = append(comments.Trailing, comment)
+		default:
+			comments.Inner = append(comments.Inner, comment)
+		}
+	}
+
+	return comments
+}
+
+// comments returns the comments attached to the walker or of the walked program, nil if not known
+func (w *Walker) comments() ast.CommentMap {
+	if w.Comments != nil {
+		return w.Comments
+	}
+	if w.program != nil {
+		return w.program.Comments
+	}
+	if program, ok := w.Root.(*ast.Program); ok {
+		return program.Comments
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package walker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestComments(t *testing.T) {
+	src := "/** Adds */\nfunction add(a /* first */, b) {\n  // nothing\n}\nif (a) /* then */ {}"
+	program, err := parser.ParseFile(nil, "", src, parser.StoreComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attached, called []string
+	visitor := &VisitorImpl{}
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			comments := CurrentMetadata(metadata).Comments()
+			for kind, list := range [][]*ast.Comment{comments.Leading, comments.Inner, comments.Trailing} {
+				for _, comment := range list {
+					attached = append(attached, fmt.Sprintf("%T %v %q", node, []string{"leading", "inner", "trailing"}[kind], comment.Text))
+				}
+			}
+			return nil
+		},
+		OnComment: func(comment *ast.Comment, metadata []Metadata) error {
+			called = append(called, fmt.Sprintf("%T %q", CurrentMetadata(metadata).Node(), comment.Text))
+			return nil
+		},
+	})
+	NewWalker(visitor).Begin(program)
+
+	expected := []string{
+		`*ast.FunctionStatement leading "* Adds "`,
+		`*ast.Identifier trailing " first "`,
+		`*ast.BlockStatement inner " nothing"`,
+		`*ast.BlockStatement leading " then "`,
+	}
+	if strings.Join(attached, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Failed, comments not correct\n%v", strings.Join(attached, "\n"))
+	}
+	if len(called) != len(expected) || called[0] != `*ast.FunctionStatement "* Adds "` {
+		t.Errorf("Failed, OnComment not called for each comment, %v", called)
+	}
+
+	// Without stored comments there are none
+	program, err = parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called = nil
+	NewWalker(visitor).Begin(program)
+	if len(called) != 0 {
+		t.Errorf("Failed, expected no comments, %v", called)
+	}
+}
@@ -0,0 +1,155 @@
+package constant
+
+import (
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+)
+
+// expression parses the source of a single expression
+func expression(t *testing.T, src string) ast.Expression {
+	program, err := parser.ParseFile(nil, "", "("+src+")", 0)
+	if err != nil {
+		t.Fatalf("Failed, %v", err)
+	}
+
+	return program.Body[0].(*ast.ExpressionStatement).Expression
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`1 + 2 * 3`, "7"},
+		{`"a" + "b" + 1 + 2`, `"ab12"`},
+		{`1 + 2 + "a"`, `"3a"`},
+		{`"5" - 2`, "3"},
+		{`"5" * "2"`, "10"},
+		{`1 / 0`, "Infinity"},
+		{`0 / 0`, "NaN"},
+		{`-7 % 3`, "-1"},
+		{`0.1 + 0.2`, "0.30000000000000004"},
+		{`1e21 + 0`, "1e+21"},
+		{`1 / 3e7`, "3.3333333333333334e-8"},
+		{`"" + 1e-7`, `"1e-7"`},
+		{`"" + 0.000001`, `"0.000001"`},
+		{`"" + 1.5e-7`, `"1.5e-7"`},
+		{`!""`, "true"},
+		{`!!"0"`, "true"},
+		{`typeof null`, `"object"`},
+		{`typeof "x"`, `"string"`},
+		{`void 0`, "undefined"},
+		{`~5`, "-6"},
+		{`-1 >>> 28`, "15"},
+		{`1 << 33`, "2"},
+		{`4294967296 | 0`, "0"},
+		{`"b" > "a"`, "true"},
+		{`"10" < "9"`, "true"},
+		{`"10" < 9`, "false"},
+		{`NaN <= NaN`, "false"},
+		{`null == undefined`, "true"},
+		{`null === undefined`, "false"},
+		{`"1" == 1`, "true"},
+		{`true == "1"`, "true"},
+		{`null == 0`, "false"},
+		{`[1, 2] + ""`, `"1,2"`},
+		{`[] + {}`, `"[object Object]"`},
+		{`[1, [2, 3]]`, "[1, [2, 3]]"},
+		{`{a: 1, "b": "x"}`, `{"a": 1, "b": "x"}`},
+		{`1 ? "yes" : x`, `"yes"`},
+		{`0 || "default"`, `"default"`},
+		{`0 && x`, "0"},
+		{`"a" + x`, "unknown"},
+		{`x ? 1 : 2`, "unknown"},
+		{`[x]`, "unknown"},
+		{`[] == []`, "unknown"},
+		{`"a" in {}`, "unknown"},
+		{`+"0x1f"`, "31"},
+		{`+" 12 "`, "12"},
+		{`+"1_000"`, "NaN"},
+		{`(1, 2)`, "2"},
+		{"String.fromCharCode(72)", "unknown"},
+	}
+
+	for i, test := range tests {
+		if result := Evaluate(expression(t, test.src)).String(); result != test.expected {
+			t.Errorf("[%v] Failed, %v: %v != %v", i, test.src, test.expected, result)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	evaluator := &Evaluator{
+		Resolve: func(identifier *ast.Identifier) Value {
+			if identifier.Name == "base" {
+				return NewString("/api")
+			}
+			return UnknownValue
+		},
+	}
+
+	if result := evaluator.Evaluate(expression(t, `base + "/x"`)); result.ToString() != "/api/x" {
+		t.Errorf("Failed, /api/x != %v", result)
+	}
+	if result := evaluator.Evaluate(expression(t, `undefined`)); result.Known() {
+		t.Errorf("Failed, expected unknown, got %v", result)
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`var base = "/api"; fetch(base + "/x")`, `"/api/x"`},
+		{`var a = 2, b = a * 3; fetch(b + 1)`, "7"},
+		{`var a = 1; a = 2; fetch(a)`, "unknown"},
+		{`var a = 1; a++; fetch(a)`, "unknown"},
+		{`var a = 1; var a = 2; fetch(a)`, "unknown"},
+		{`fetch(a); var a = 1`, "unknown"},
+		{`var a = 1; function f(a) { fetch(a) }`, "unknown"},
+		{`var a = "x"; function f() { fetch(a + "y") } f()`, `"xy"`},
+		{`var a = "x"; (function() { fetch(a + "y") })()`, `"xy"`},
+		{`g(); var a = "/api"; function g() { fetch(a) }`, "unknown"},
+		{`var a = "/api"; function g() { fetch(a) } function h() { g() }`, "unknown"},
+		{`var a = (function() { return a })(); fetch(a)`, "unknown"},
+		{`if (c) { var a = 1 } fetch(a)`, "unknown"},
+		{`for (;c;) { var a = 1 } fetch(a)`, "unknown"},
+		{`function f() { var a = 1; if (c) { fetch(a) } }`, "1"},
+		{`function f() { var a = 1; eval(""); fetch(a) }`, "unknown"},
+		{`var a = 1; with (o) { fetch(a) }`, "unknown"},
+		{`var a = [1]; fetch(a)`, "unknown"},
+		{`var a = b; var b = a; fetch(a)`, "unknown"},
+		{`fetch(undefined)`, "undefined"},
+		{`undefined = 1; fetch(undefined)`, "unknown"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		propagator := NewPropagator()
+		var argument ast.Expression
+		visitor := &walker.VisitorImpl{}
+		visitor.AddHook(propagator.Hook())
+		visitor.AddHook(&walker.Hook{
+			OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+				if call, ok := node.(*ast.CallExpression); ok && len(call.ArgumentList) > 0 {
+					argument = call.ArgumentList[0]
+				}
+				return nil
+			},
+		})
+		walker.NewWalker(visitor).Begin(program)
+
+		if result := propagator.Value(argument).String(); result != test.expected {
+			t.Errorf("[%v] Failed, %v: %v != %v", i, test.src, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package constant
+
+import (
+	"math"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+// Evaluator computes the values of expressions built from literals and operators
+type Evaluator struct {
+	// Resolve returns the value of an identifier, nil resolves only undefined, NaN and Infinity
+	Resolve func(identifier *ast.Identifier) Value
+}
+
+// Evaluate returns the value of the expression, unknown if it depends on anything but literals
+func Evaluate(expression ast.Expression) Value {
+	return (&Evaluator{}).Evaluate(expression)
+}
+
+// Evaluate returns the value of the expression, unknown if it cannot be computed
+func (e *Evaluator) Evaluate(expression ast.Expression) Value {
+	switch n := expression.(type) {
+	case *ast.NumberLiteral:
+		switch value := n.Value.(type) {
+		case int64:
+			return NewNumber(float64(value))
+		case float64:
+			return NewNumber(value)
+		}
+	case *ast.StringLiteral:
+		return NewString(n.Value)
+	case *ast.BooleanLiteral:
+		return NewBoolean(n.Value)
+	case *ast.NullLiteral:
+		return NullValue
+	case *ast.Identifier:
+		if e.Resolve != nil {
+			return e.Resolve(n)
+		}
+		return Global(n.Name)
+	case *ast.ArrayLiteral:
+		value := Value{Kind: Array, Elements: []Value{}}
+		for _, element := range n.Value {
+			if element == nil {
+				// A hole in the array
+				value.Elements = append(value.Elements, UndefinedValue)
+				continue
+			}
+			v := e.Evaluate(element)
+			if !v.Known() {
+				return UnknownValue
+			}
+			value.Elements = append(value.Elements, v)
+		}
+		return value
+	case *ast.ObjectLiteral:
+		value := Value{Kind: Object, Properties: map[string]Value{}}
+		for _, property := range n.Value {
+			if property.Kind != "value" {
+				// Getters and setters
+				return UnknownValue
+			}
+			v := e.Evaluate(property.Value)
+			if !v.Known() {
+				return UnknownValue
+			}
+			if _, exists := value.Properties[property.Key]; !exists {
+				value.Keys = append(value.Keys, property.Key)
+			}
+			value.Properties[property.Key] = v
+		}
+		return value
+	case *ast.UnaryExpression:
+		return e.unary(n)
+	case *ast.BinaryExpression:
+		return e.binary(n)
+	case *ast.ConditionalExpression:
+		test := e.Evaluate(n.Test)
+		if !test.Known() {
+			return UnknownValue
+		}
+		if test.ToBoolean() {
+			return e.Evaluate(n.Consequent)
+		}
+		return e.Evaluate(n.Alternate)
+	case *ast.SequenceExpression:
+		value := UnknownValue
+		for _, expression := range n.Sequence {
+			if value = e.Evaluate(expression); !value.Known() {
+				return UnknownValue
+			}
+		}
+		return value
+	}
+
+	return UnknownValue
+}
+
+// Global returns the value of the global constants undefined, NaN and Infinity, unknown for other names
+func Global(name string) Value {
+	switch name {
+	case "undefined":
+		return UndefinedValue
+	case "NaN":
+		return NewNumber(math.NaN())
+	case "Infinity":
+		return NewNumber(math.Inf(1))
+	}
+
+	return UnknownValue
+}
+
+func (e *Evaluator) unary(n *ast.UnaryExpression) Value {
+	operand := e.Evaluate(n.Operand)
+	if !operand.Known() {
+		return UnknownValue
+	}
+
+	switch n.Operator {
+	case token.PLUS:
+		return NewNumber(operand.ToNumber())
+	case token.MINUS:
+		return NewNumber(-operand.ToNumber())
+	case token.NOT:
+		return NewBoolean(!operand.ToBoolean())
+	case token.BITWISE_NOT:
+		return NewNumber(float64(^operand.toInt32()))
+	case token.TYPEOF:
+		return NewString(operand.TypeOf())
+	case token.VOID:
+		return UndefinedValue
+	}
+
+	// delete, ++ and -- have side effects
+	return UnknownValue
+}
+
+func (e *Evaluator) binary(n *ast.BinaryExpression) Value {
+	left := e.Evaluate(n.Left)
+	if !left.Known() {
+		return UnknownValue
+	}
+
+	// The right operand is not evaluated if the result is known from the left
+	switch n.Operator {
+	case token.LOGICAL_AND:
+		if !left.ToBoolean() {
+			return left
+		}
+		return e.Evaluate(n.Right)
+	case token.LOGICAL_OR:
+		if left.ToBoolean() {
+			return left
+		}
+		return e.Evaluate(n.Right)
+	}
+
+	right := e.Evaluate(n.Right)
+	if !right.Known() {
+		return UnknownValue
+	}
+
+	return BinaryOperation(n.Operator, left, right)
+}
+
+// BinaryOperation returns the result of the operator applied to known values, unknown if it cannot be computed
+func BinaryOperation(operator token.Token, left, right Value) Value {
+	switch operator {
+	case token.PLUS:
+		l, r := left.toPrimitive(), right.toPrimitive()
+		if l.Kind == String || r.Kind == String {
+			return NewString(l.ToString() + r.ToString())
+		}
+		return NewNumber(l.ToNumber() + r.ToNumber())
+	case token.MINUS:
+		return NewNumber(left.ToNumber() - right.ToNumber())
+	case token.MULTIPLY:
+		return NewNumber(left.ToNumber() * right.ToNumber())
+	case token.SLASH:
+		return NewNumber(left.ToNumber() / right.ToNumber())
+	case token.REMAINDER:
+		return NewNumber(math.Mod(left.ToNumber(), right.ToNumber()))
+	case token.AND:
+		return NewNumber(float64(left.toInt32() & right.toInt32()))
+	case token.OR:
+		return NewNumber(float64(left.toInt32() | right.toInt32()))
+	case token.EXCLUSIVE_OR:
+		return NewNumber(float64(left.toInt32() ^ right.toInt32()))
+	case token.SHIFT_LEFT:
+		return NewNumber(float64(left.toInt32() << (right.toUint32() & 31)))
+	case token.SHIFT_RIGHT:
+		return NewNumber(float64(left.toInt32() >> (right.toUint32() & 31)))
+	case token.UNSIGNED_SHIFT_RIGHT:
+		return NewNumber(float64(left.toUint32() >> (right.toUint32() & 31)))
+	case token.LESS:
+		return compare(left, right, false)
+	case token.GREATER:
+		return compare(right, left, false)
+	case token.LESS_OR_EQUAL:
+		return compare(right, left, true)
+	case token.GREATER_OR_EQUAL:
+		return compare(left, right, true)
+	case token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		equal, ok := strictEquals(left, right)
+		if !ok {
+			return UnknownValue
+		}
+		return NewBoolean(equal == (operator == token.STRICT_EQUAL))
+	case token.EQUAL, token.NOT_EQUAL:
+		equal, ok := looseEquals(left, right)
+		if !ok {
+			return UnknownValue
+		}
+		return NewBoolean(equal == (operator == token.EQUAL))
+	}
+
+	// in and instanceof depend on objects at runtime
+	return UnknownValue
+}
+
+// compare returns left < right, or its negation as used by <= and >=.
+// Comparisons involving NaN are false, negated or not.
+func compare(left, right Value, negate bool) Value {
+	l, r := left.toPrimitive(), right.toPrimitive()
+	if l.Kind == String && r.Kind == String {
+		return NewBoolean((l.Str < r.Str) != negate)
+	}
+
+	x, y := l.ToNumber(), r.ToNumber()
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return NewBoolean(false)
+	}
+
+	return NewBoolean((x < y) != negate)
+}
+
+// strictEquals returns the result of ===, ok is false if it cannot be computed
+func strictEquals(left, right Value) (equal bool, ok bool) {
+	if !left.IsPrimitive() || !right.IsPrimitive() {
+		// Objects are compared by identity
+		if left.Kind != right.Kind {
+			return false, true
+		}
+		return false, false
+	}
+	if left.Kind != right.Kind {
+		return false, true
+	}
+
+	switch left.Kind {
+	case Undefined, Null:
+		return true, true
+	case Boolean:
+		return left.Bool == right.Bool, true
+	case Number:
+		return left.Number == right.Number, true
+	case String:
+		return left.Str == right.Str, true
+	}
+
+	return false, false
+}
+
+// looseEquals returns the result of ==, ok is false if it cannot be computed
+func looseEquals(left, right Value) (equal bool, ok bool) {
+	if left.Kind == right.Kind {
+		return strictEquals(left, right)
+	}
+
+	isNullish := func(v Value) bool {
+		return v.Kind == Undefined || v.Kind == Null
+	}
+	if isNullish(left) || isNullish(right) {
+		return isNullish(left) && isNullish(right), true
+	}
+
+	if !left.IsPrimitive() && !right.IsPrimitive() {
+		return false, false
+	}
+	l, r := left.toPrimitive(), right.toPrimitive()
+	if l.Kind == String && r.Kind == String {
+		return l.Str == r.Str, true
+	}
+
+	return l.ToNumber() == r.ToNumber(), true
+}
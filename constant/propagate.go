@@ -0,0 +1,210 @@
+package constant
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/wolfgarnet/walker"
+)
+
+// Propagator resolves variables assigned exactly once, by a constant initializer, to their values.
+// The initializer must be a statement of the body of the function or program, executed before the read.
+// The values of expressions are available after the walk.
+type Propagator struct {
+	// reads maps identifiers reading variables to their bindings
+	reads map[*ast.Identifier]walker.Binding
+	// nested maps the reads to the outermost function enclosing them in the scope of the variable
+	nested       map[*ast.Identifier]ast.Node
+	initializers map[walker.Binding][]*ast.VariableExpression
+	// unconditional lists the initializers of statements of the body of their function or program
+	unconditional map[*ast.VariableExpression]bool
+	// assigned lists the variables with values not given by an initializer
+	assigned map[walker.Binding]bool
+	values   map[ast.Expression]Value
+	pending  map[walker.Binding]bool
+}
+
+// NewPropagator returns a new instance
+func NewPropagator() *Propagator {
+	return &Propagator{
+		reads:         map[*ast.Identifier]walker.Binding{},
+		nested:        map[*ast.Identifier]ast.Node{},
+		initializers:  map[walker.Binding][]*ast.VariableExpression{},
+		unconditional: map[*ast.VariableExpression]bool{},
+		assigned:      map[walker.Binding]bool{},
+		values:        map[ast.Expression]Value{},
+		pending:       map[walker.Binding]bool{},
+	}
+}
+
+// Hook returns the hook collecting the variables
+func (p *Propagator) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode: p.onNode,
+	}
+}
+
+func (p *Propagator) onNode(node ast.Node, metadata []walker.Metadata) error {
+	switch n := node.(type) {
+	case *ast.VariableExpression:
+		binding := walker.ResolveBinding(metadata, n.Name)
+		if n.Initializer != nil {
+			p.initializers[binding] = append(p.initializers[binding], n)
+			p.unconditional[n] = isBodyStatement(metadata)
+		}
+		if isDynamic(metadata, binding) {
+			p.assigned[binding] = true
+		}
+	case *ast.Identifier:
+		access := walker.IdentifierAccess(metadata)
+		if access == 0 {
+			// Parameters, function and catch names are assigned when called or caught
+			switch walker.ParentMetadata(metadata).Node().(type) {
+			case *ast.FunctionLiteral, *ast.CatchStatement:
+				p.assigned[walker.ResolveBinding(metadata, n.Name)] = true
+			}
+			return nil
+		}
+
+		binding := walker.ResolveBinding(metadata, n.Name)
+		if access&walker.Write != 0 {
+			p.assigned[binding] = true
+		}
+		if access&walker.Read != 0 {
+			p.reads[n] = binding
+			p.nested[n] = nestedFunction(metadata, binding.Scope)
+		}
+	}
+
+	return nil
+}
+
+// isBodyStatement returns true if the current variable is declared by a statement of the body of a function or program,
+// which is not nested in a conditional or loop
+func isBodyStatement(metadata []walker.Metadata) bool {
+	l := len(metadata)
+	if l < 3 {
+		return false
+	}
+	if _, ok := metadata[l-2].Node().(*ast.VariableStatement); !ok {
+		return false
+	}
+
+	switch metadata[l-3].Node().(type) {
+	case *ast.Program:
+		return true
+	case *ast.BlockStatement:
+		if l > 3 {
+			_, ok := metadata[l-4].Node().(*ast.FunctionLiteral)
+			return ok
+		}
+	}
+
+	return false
+}
+
+// nestedFunction returns the outermost function enclosing the current node in the scope, nil if none.
+// The *ast.FunctionStatement is returned for function declarations.
+func nestedFunction(metadata []walker.Metadata, scope ast.Node) ast.Node {
+	for i := range metadata {
+		if scope == nil || metadata[i].Node() != scope {
+			continue
+		}
+
+		for j := i + 1; j < len(metadata); j++ {
+			if function, ok := metadata[j].Node().(*ast.FunctionLiteral); ok {
+				if statement, ok := metadata[j-1].Node().(*ast.FunctionStatement); ok {
+					return statement
+				}
+				return function
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// isDynamic returns true if the scope of the binding calls eval, which may assign the variable
+func isDynamic(metadata []walker.Metadata, binding walker.Binding) bool {
+	for _, md := range metadata {
+		if md.Node() == binding.Scope {
+			dynamic, _ := md[walker.Dynamic].(bool)
+			return dynamic
+		}
+	}
+
+	return false
+}
+
+// Value returns the value of the expression, resolving constant variables, unknown if it cannot be computed
+func (p *Propagator) Value(expression ast.Expression) Value {
+	if value, ok := p.values[expression]; ok {
+		return value
+	}
+
+	value := (&Evaluator{Resolve: p.resolve}).Evaluate(expression)
+	p.values[expression] = value
+	return value
+}
+
+// Constant returns the initializer of the variable if it is assigned exactly once by it,
+// in a statement of the body of its function or program, nil otherwise
+func (p *Propagator) Constant(binding walker.Binding) *ast.VariableExpression {
+	if binding.Scope == nil || binding.Unknown || p.assigned[binding] || len(p.initializers[binding]) != 1 {
+		return nil
+	}
+
+	declaration := p.initializers[binding][0]
+	if !p.unconditional[declaration] {
+		return nil
+	}
+
+	return declaration
+}
+
+// resolve returns the value of a constant variable read by the identifier.
+// Reads which may be executed before the initializer see undefined, and are unknown.
+func (p *Propagator) resolve(identifier *ast.Identifier) Value {
+	binding, ok := p.reads[identifier]
+	if !ok {
+		return UnknownValue
+	}
+	if binding.Scope == nil && !binding.Unknown && !p.assigned[binding] {
+		return Global(identifier.Name)
+	}
+
+	declaration := p.Constant(binding)
+	if declaration == nil || !p.initialized(binding, declaration, identifier) || p.pending[binding] {
+		return UnknownValue
+	}
+
+	p.pending[binding] = true
+	value := p.Value(declaration.Initializer)
+	delete(p.pending, binding)
+
+	// Arrays and objects can be modified through the variable
+	if !value.IsPrimitive() {
+		return UnknownValue
+	}
+
+	return value
+}
+
+// initialized returns true if the initializer of the declaration is executed before the identifier is read
+func (p *Propagator) initialized(binding walker.Binding, declaration *ast.VariableExpression, identifier *ast.Identifier) bool {
+	switch function := p.nested[identifier].(type) {
+	case *ast.FunctionLiteral:
+		// Function expressions are created when evaluated, they cannot be called before
+		return function.Idx0() >= declaration.Idx1()
+	case *ast.FunctionStatement:
+		// Function declarations are created first, all their references must follow the initializer
+		name := walker.Binding{Scope: binding.Scope, Name: function.Function.Name.Name}
+		for read, b := range p.reads {
+			if b == name && (read.Idx < declaration.Idx1() || p.nested[read] != nil) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return identifier.Idx >= declaration.Idx1()
+}
@@ -0,0 +1,270 @@
+// Package constant evaluates expressions whose value is known without running the program.
+package constant
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of a value
+type Kind int
+
+const (
+	// Unknown is the kind of values that cannot be computed statically
+	Unknown Kind = iota
+	Undefined
+	Null
+	Boolean
+	Number
+	String
+	Array
+	Object
+)
+
+// String returns the name of the kind, as given by typeof for primitives
+func (k Kind) String() string {
+	switch k {
+	case Undefined:
+		return "undefined"
+	case Null:
+		return "null"
+	case Boolean:
+		return "boolean"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// Value is the result of an evaluation
+type Value struct {
+	Kind   Kind
+	Bool   bool
+	Number float64
+	Str    string
+	// Elements of arrays
+	Elements []Value
+	// Keys of objects, in order, and their values
+	Keys       []string
+	Properties map[string]Value
+}
+
+var (
+	UnknownValue   = Value{}
+	UndefinedValue = Value{Kind: Undefined}
+	NullValue      = Value{Kind: Null}
+)
+
+// NewBoolean returns a boolean value
+func NewBoolean(b bool) Value {
+	return Value{Kind: Boolean, Bool: b}
+}
+
+// NewNumber returns a number value
+func NewNumber(f float64) Value {
+	return Value{Kind: Number, Number: f}
+}
+
+// NewString returns a string value
+func NewString(s string) Value {
+	return Value{Kind: String, Str: s}
+}
+
+// Known returns true if the value was computed
+func (v Value) Known() bool {
+	return v.Kind != Unknown
+}
+
+// IsPrimitive returns true if the value is not an array or an object
+func (v Value) IsPrimitive() bool {
+	return v.Kind != Array && v.Kind != Object
+}
+
+// ToBoolean converts the value to a boolean, following JavaScript
+func (v Value) ToBoolean() bool {
+	switch v.Kind {
+	case Boolean:
+		return v.Bool
+	case Number:
+		return v.Number != 0 && !math.IsNaN(v.Number)
+	case String:
+		return v.Str != ""
+	case Array, Object:
+		return true
+	}
+
+	return false
+}
+
+// ToNumber converts the value to a number, following JavaScript
+func (v Value) ToNumber() float64 {
+	switch v.Kind {
+	case Boolean:
+		if v.Bool {
+			return 1
+		}
+		return 0
+	case Number:
+		return v.Number
+	case String:
+		return stringToNumber(v.Str)
+	case Null:
+		return 0
+	case Array:
+		return stringToNumber(v.ToString())
+	}
+
+	return math.NaN()
+}
+
+// stringToNumber parses the string as a number literal, NaN if it is not one
+func stringToNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return 0
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if i, err := strconv.ParseUint(s[2:], 16, 64); err == nil {
+			return float64(i)
+		}
+		return math.NaN()
+	}
+	// Go accepts forms JavaScript does not
+	if strings.ContainsAny(s, "_xXpPnN") {
+		return math.NaN()
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+
+	return f
+}
+
+// ToString converts the value to a string, following JavaScript
+func (v Value) ToString() string {
+	switch v.Kind {
+	case Undefined:
+		return "undefined"
+	case Null:
+		return "null"
+	case Boolean:
+		return strconv.FormatBool(v.Bool)
+	case Number:
+		return numberToString(v.Number)
+	case String:
+		return v.Str
+	case Array:
+		elements := make([]string, len(v.Elements))
+		for i, e := range v.Elements {
+			if e.Kind != Undefined && e.Kind != Null {
+				elements[i] = e.ToString()
+			}
+		}
+		return strings.Join(elements, ",")
+	case Object:
+		return "[object Object]"
+	}
+
+	return ""
+}
+
+// numberToString formats the number as JavaScript does
+func numberToString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	// Go pads the exponent to two digits
+	s = strings.Replace(s, "e+0", "e+", 1)
+	return strings.Replace(s, "e-0", "e-", 1)
+}
+
+// toInt32 converts the value to a 32 bit integer, following JavaScript
+func (v Value) toInt32() int32 {
+	return int32(v.toUint32())
+}
+
+// toUint32 converts the value to an unsigned 32 bit integer, following JavaScript
+func (v Value) toUint32() uint32 {
+	f := v.ToNumber()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+
+	f = math.Mod(math.Trunc(f), 1<<32)
+	if f < 0 {
+		f += 1 << 32
+	}
+
+	return uint32(f)
+}
+
+// toPrimitive converts arrays and objects to their string value
+func (v Value) toPrimitive() Value {
+	if v.IsPrimitive() {
+		return v
+	}
+
+	return NewString(v.ToString())
+}
+
+// TypeOf returns the result of the typeof operator
+func (v Value) TypeOf() string {
+	switch v.Kind {
+	case Null, Array, Object:
+		return "object"
+	}
+
+	return v.Kind.String()
+}
+
+// String displays the value as JavaScript source
+func (v Value) String() string {
+	switch v.Kind {
+	case Unknown:
+		return "unknown"
+	case String:
+		return strconv.Quote(v.Str)
+	case Array:
+		elements := make([]string, len(v.Elements))
+		for i, e := range v.Elements {
+			elements[i] = e.String()
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case Object:
+		properties := make([]string, len(v.Keys))
+		for i, key := range v.Keys {
+			properties[i] = strconv.Quote(key) + ": " + v.Properties[key].String()
+		}
+		return "{" + strings.Join(properties, ", ") + "}"
+	}
+
+	return v.ToString()
+}
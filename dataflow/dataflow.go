@@ -0,0 +1,117 @@
+// Package dataflow solves dataflow problems over the control flow graphs of the cfg package.
+package dataflow
+
+import (
+	"github.com/wolfgarnet/walker/cfg"
+)
+
+// Fact is an element of the lattice of an analysis
+type Fact interface{}
+
+// Direction of an analysis
+type Direction int
+
+const (
+	Forward Direction = iota
+	Backward
+)
+
+// Lattice defines the facts of an analysis
+type Lattice interface {
+	// Bottom returns the least element, the initial fact of every block
+	Bottom() Fact
+	// Join returns the least upper bound of the facts
+	Join(a, b Fact) Fact
+	// Equal returns true if the facts are the same
+	Equal(a, b Fact) bool
+}
+
+// Analysis is a dataflow problem
+type Analysis interface {
+	Lattice
+	Direction() Direction
+	// Boundary returns the fact at the entry of the graph, or the exit for backward analyses
+	Boundary(graph *cfg.Graph) Fact
+	// Transfer returns the fact after the block, given the fact before it.
+	// For backward analyses, the fact at the end of the block is given, and the fact at the start is returned.
+	Transfer(block *cfg.Block, fact Fact) Fact
+}
+
+// Result holds the facts at the start and end of every block
+type Result struct {
+	In  map[*cfg.Block]Fact
+	Out map[*cfg.Block]Fact
+}
+
+// Solve computes the fixed point of the analysis over the graph, using a worklist.
+// Facts flowing along exception edges are the join of the facts at the start and end of the block,
+// as the exception can happen anywhere in it.
+func Solve(graph *cfg.Graph, analysis Analysis) *Result {
+	result := &Result{
+		In:  map[*cfg.Block]Fact{},
+		Out: map[*cfg.Block]Fact{},
+	}
+
+	queued := map[*cfg.Block]bool{}
+	var worklist []*cfg.Block
+	enqueue := func(block *cfg.Block) {
+		if !queued[block] {
+			queued[block] = true
+			worklist = append(worklist, block)
+		}
+	}
+
+	for _, block := range graph.Blocks {
+		result.In[block] = analysis.Bottom()
+		result.Out[block] = analysis.Bottom()
+		enqueue(block)
+	}
+
+	forward := analysis.Direction() == Forward
+	for len(worklist) > 0 {
+		block := worklist[0]
+		worklist = worklist[1:]
+		queued[block] = false
+
+		if forward {
+			in := analysis.Bottom()
+			if block == graph.Entry {
+				in = analysis.Boundary(graph)
+			}
+			for _, edge := range block.Preds {
+				fact := result.Out[edge.From]
+				if edge.Kind == cfg.Exception {
+					fact = analysis.Join(result.In[edge.From], fact)
+				}
+				in = analysis.Join(in, fact)
+			}
+
+			out := analysis.Transfer(block, in)
+			if !analysis.Equal(in, result.In[block]) || !analysis.Equal(out, result.Out[block]) {
+				result.In[block], result.Out[block] = in, out
+				for _, edge := range block.Succs {
+					enqueue(edge.To)
+				}
+			}
+			continue
+		}
+
+		out := analysis.Bottom()
+		if block == graph.Exit {
+			out = analysis.Boundary(graph)
+		}
+		for _, edge := range block.Succs {
+			out = analysis.Join(out, result.In[edge.To])
+		}
+
+		in := analysis.Transfer(block, out)
+		if !analysis.Equal(in, result.In[block]) || !analysis.Equal(out, result.Out[block]) {
+			result.In[block], result.Out[block] = in, out
+			for _, edge := range block.Preds {
+				enqueue(edge.From)
+			}
+		}
+	}
+
+	return result
+}
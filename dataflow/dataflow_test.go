@@ -0,0 +1,80 @@
+package dataflow
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+)
+
+// diagnostics returns the codes and variable names of the diagnostics of all graphs, sorted
+func diagnostics(t *testing.T, src string) string {
+	program, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		t.Fatalf("Failed, %v", err)
+	}
+
+	var result []string
+	for _, r := range Analyze(program) {
+		var found []*walker.Diagnostic
+		found = append(found, r.UseBeforeAssignment()...)
+		found = append(found, r.DeadStores()...)
+		for _, d := range found {
+			result = append(result, d.Code+" "+strings.Fields(d.Message)[0])
+		}
+	}
+	sort.Strings(result)
+
+	return strings.Join(result, ", ")
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`function f() { var x; return x }`, "use-before-assignment x"},
+		{`function f() { var x = 1; return x }`, ""},
+		{`function f(a) { var x; if (a) { x = 1 } return x }`, "use-before-assignment x"},
+		{`function f(a) { var x; if (a) { x = 1 } else { x = 2 } return x }`, ""},
+		{`function f() { var x = 1; x = 2; return x }`, "dead-store x"},
+		{`function f() { var x = 1; x += 2; return x }`, ""},
+		{`function f(a) { a = 1 }`, "dead-store a"},
+		{`function f() { var i; for (i = 0; i < 3; i++) {} }`, ""},
+		{`function f() { var x = 1; return function() { return x } }`, ""},
+		{`function f() { var x = 1; x = 2; eval("x") }`, ""},
+		{`function f() { var x = 1; try { x = 2; g() } catch (e) { return x } return 0 }`, ""},
+		{`function f() { var x; try { x = g() } catch (e) { return x } return 0 }`, "use-before-assignment x"},
+		{`function f() { for (var k in o) { } }`, ""},
+		{`function f() { return g(); function g() { return 1 } }`, ""},
+		{`var x = 1; x = 2`, ""},
+	}
+
+	for i, test := range tests {
+		if result := diagnostics(t, test.src); result != test.expected {
+			t.Errorf("[%v] Failed, %q != %q", i, test.expected, result)
+		}
+	}
+}
+
+func TestUses(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", `function f(a) { var x = 1; if (a) { x = 2 } return x }`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Analyze(program)[1]
+	for _, use := range r.Uses {
+		if use.Binding.Name != "x" {
+			continue
+		}
+		if len(use.Definitions) != 2 {
+			t.Errorf("Failed, number of definitions not correct, 2 != %v", len(use.Definitions))
+		}
+		return
+	}
+
+	t.Error("Failed, use of x not found")
+}
@@ -0,0 +1,449 @@
+package dataflow
+
+import (
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+	"github.com/wolfgarnet/walker/cfg"
+)
+
+const (
+	// UseBeforeAssignmentCode is the code of diagnostics reporting reads of variables that may not be assigned yet
+	UseBeforeAssignmentCode = "use-before-assignment"
+	// DeadStoreCode is the code of diagnostics reporting assigned values that are never read
+	DeadStoreCode = "dead-store"
+)
+
+// DefinitionKind describes how a definition assigns its variable
+type DefinitionKind int
+
+const (
+	// Declaration is the implicit undefined value of a declared variable at the entry of its function
+	Declaration DefinitionKind = iota
+	Parameter
+	// Function is the hoisted value of a function declaration or the name of a function expression
+	Function
+	Assignment
+)
+
+// Definition is a point assigning a value to a variable
+type Definition struct {
+	Binding walker.Binding
+	Kind    DefinitionKind
+	// Node is the assigning node: a VariableExpression, AssignExpression, UnaryExpression,
+	// the Identifier of a for in statement or a parameter, a CatchStatement or a FunctionLiteral.
+	// It is the declaring function or program for declarations.
+	Node ast.Node
+	// Uses lists the reads reached by the definition
+	Uses []*Use
+
+	block *cfg.Block
+}
+
+// Use is a read of a variable
+type Use struct {
+	Identifier *ast.Identifier
+	Binding    walker.Binding
+	// Definitions lists the definitions that may reach the read
+	Definitions []*Definition
+}
+
+// DefinitionSet is the fact of the reaching definitions analysis
+type DefinitionSet map[*Definition]bool
+
+// event is a read or a definition in a block, in order of evaluation
+type event struct {
+	identifier *ast.Identifier
+	definition *Definition
+}
+
+// ReachingDefinitions is the reaching definitions analysis of a graph.
+// Only the local variables of the graph are tracked, variables referenced by
+// nested functions are not, as calls can read and assign them at any point.
+type ReachingDefinitions struct {
+	Graph       *cfg.Graph
+	Definitions []*Definition
+	Uses        []*Use
+	Result      *Result
+
+	// Skipped is true if the function is a dynamic scope, or a with statement in it is, and no variables are tracked
+	Skipped bool
+
+	metadata   walker.Metadata
+	references map[file.Idx]*walker.Reference
+	tracked    map[walker.Binding]bool
+	entry      []*Definition
+	events     map[*cfg.Block][]event
+}
+
+// NewReachingDefinitions returns a new instance for the graph, the references of the program must be collected
+func NewReachingDefinitions(graph *cfg.Graph, references *walker.ReferenceCollector) *ReachingDefinitions {
+	r := &ReachingDefinitions{
+		Graph:      graph,
+		metadata:   references.Metadata,
+		references: map[file.Idx]*walker.Reference{},
+		tracked:    map[walker.Binding]bool{},
+		events:     map[*cfg.Block][]event{},
+	}
+
+	for _, reference := range references.References {
+		r.references[reference.Idx] = reference
+	}
+	for _, reference := range references.References {
+		if reference.Binding.Unknown && enclosingFunction(reference.Scopes) == graph.Node {
+			r.Skipped = true
+		}
+	}
+
+	for _, reference := range references.References {
+		binding := reference.Binding
+		if binding.Scope == nil || binding.Unknown || owner(reference.Scopes, binding.Scope) != graph.Node {
+			continue
+		}
+		if _, ok := r.tracked[binding]; !ok {
+			r.tracked[binding] = !r.Skipped
+		}
+		if enclosingFunction(reference.Scopes) != graph.Node {
+			r.tracked[binding] = false
+		}
+	}
+
+	return r
+}
+
+// Analyze computes the reaching definitions of the program and each function in it, the program first
+func Analyze(program *ast.Program) []*ReachingDefinitions {
+	references := walker.NewReferenceCollector()
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(references.Hook())
+	walker.NewWalker(visitor).Begin(program)
+
+	var result []*ReachingDefinitions
+	for _, graph := range cfg.Build(program) {
+		r := NewReachingDefinitions(graph, references)
+		r.Solve()
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// enclosingFunction returns the innermost function or program of the scopes
+func enclosingFunction(scopes []ast.Node) ast.Node {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		switch scopes[i].(type) {
+		case *ast.Program, *ast.FunctionLiteral:
+			return scopes[i]
+		}
+	}
+
+	return nil
+}
+
+// owner returns the function or program of the scope, given the scopes enclosing a reference to it
+func owner(scopes []ast.Node, scope ast.Node) ast.Node {
+	for i, s := range scopes {
+		if s == scope {
+			return enclosingFunction(scopes[:i+1])
+		}
+	}
+
+	return nil
+}
+
+// Tracked returns true if the definitions and uses of the binding are tracked by the analysis
+func (r *ReachingDefinitions) Tracked(binding walker.Binding) bool {
+	return r.tracked[binding]
+}
+
+// Solve runs the analysis and links the uses and definitions
+func (r *ReachingDefinitions) Solve() {
+	r.collectEntry()
+	for _, block := range r.Graph.Blocks {
+		for _, node := range block.Nodes {
+			r.scanNode(block, node)
+		}
+	}
+
+	r.Result = Solve(r.Graph, r)
+
+	for _, block := range r.Graph.Blocks {
+		reaching := r.Result.In[block].(DefinitionSet).copy()
+		for _, e := range r.events[block] {
+			if e.definition != nil {
+				reaching.define(e.definition)
+				continue
+			}
+
+			use := &Use{Identifier: e.identifier, Binding: r.references[e.identifier.Idx].Binding}
+			for _, definition := range r.Definitions {
+				if reaching[definition] && definition.Binding == use.Binding {
+					use.Definitions = append(use.Definitions, definition)
+					definition.Uses = append(definition.Uses, use)
+				}
+			}
+			r.Uses = append(r.Uses, use)
+		}
+	}
+}
+
+// collectEntry adds the definitions holding at the entry of the function
+func (r *ReachingDefinitions) collectEntry() {
+	var declarations []ast.Declaration
+	switch n := r.Graph.Node.(type) {
+	case *ast.Program:
+		declarations = n.DeclarationList
+	case *ast.FunctionLiteral:
+		declarations = n.DeclarationList
+		if n.Name != nil {
+			r.addEntry(n.Name.Name, Function, n)
+		}
+		for _, parameter := range n.ParameterList.List {
+			r.addEntry(parameter.Name, Parameter, parameter)
+		}
+	}
+
+	for _, declaration := range declarations {
+		if d, ok := declaration.(*ast.FunctionDeclaration); ok && d.Function.Name != nil {
+			r.addEntry(d.Function.Name.Name, Function, d.Function)
+		}
+	}
+	for _, declaration := range declarations {
+		if d, ok := declaration.(*ast.VariableDeclaration); ok {
+			for _, v := range d.List {
+				r.addEntry(v.Name, Declaration, r.Graph.Node)
+			}
+		}
+	}
+}
+
+// addEntry adds an entry definition, unless the variable already has one
+func (r *ReachingDefinitions) addEntry(name string, kind DefinitionKind, node ast.Node) {
+	binding := walker.Binding{Scope: r.Graph.Node, Name: name}
+	if !r.tracked[binding] {
+		return
+	}
+	for _, definition := range r.entry {
+		if definition.Binding == binding {
+			return
+		}
+	}
+
+	definition := &Definition{Binding: binding, Kind: kind, Node: node}
+	r.entry = append(r.entry, definition)
+	r.Definitions = append(r.Definitions, definition)
+}
+
+// scanNode records the events of a node of the block
+func (r *ReachingDefinitions) scanNode(block *cfg.Block, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.CatchStatement:
+		// Only the parameter is bound by the catch clause, the body has its own nodes
+		r.define(block, n.Parameter, Assignment, n)
+		return
+	case *ast.VariableExpression:
+		// The declaration of a for in statement, assigned by each iteration
+		r.define(block, &ast.Identifier{Name: n.Name, Idx: n.Idx}, Assignment, n)
+		return
+	}
+
+	r.scan(block, node)
+}
+
+// scan records the events of the node and its children in order of evaluation
+func (r *ReachingDefinitions) scan(block *cfg.Block, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		// Nested functions are analyzed separately
+		return
+	case *ast.Identifier:
+		reference := r.references[n.Idx]
+		if reference == nil {
+			return
+		}
+		if reference.Access&walker.Read != 0 {
+			r.use(block, n)
+		}
+		if reference.Access&walker.Write != 0 {
+			r.define(block, n, Assignment, n)
+		}
+		return
+	case *ast.AssignExpression:
+		if identifier, ok := n.Left.(*ast.Identifier); ok {
+			r.scan(block, n.Right)
+			if n.Operator != token.ASSIGN {
+				r.use(block, identifier)
+			}
+			r.define(block, identifier, Assignment, n)
+			return
+		}
+	case *ast.UnaryExpression:
+		if identifier, ok := n.Operand.(*ast.Identifier); ok && (n.Operator == token.INCREMENT || n.Operator == token.DECREMENT) {
+			r.use(block, identifier)
+			r.define(block, identifier, Assignment, n)
+			return
+		}
+	case *ast.VariableExpression:
+		if n.Initializer != nil {
+			r.scan(block, n.Initializer)
+			r.define(block, &ast.Identifier{Name: n.Name, Idx: n.Idx}, Assignment, n)
+		}
+		return
+	}
+
+	for _, child := range walker.Children(node) {
+		r.scan(block, child)
+	}
+}
+
+func (r *ReachingDefinitions) use(block *cfg.Block, identifier *ast.Identifier) {
+	reference := r.references[identifier.Idx]
+	if reference == nil || !r.tracked[reference.Binding] {
+		return
+	}
+
+	r.events[block] = append(r.events[block], event{identifier: identifier})
+}
+
+func (r *ReachingDefinitions) define(block *cfg.Block, identifier *ast.Identifier, kind DefinitionKind, node ast.Node) {
+	reference := r.references[identifier.Idx]
+	if reference == nil || !r.tracked[reference.Binding] {
+		return
+	}
+
+	definition := &Definition{Binding: reference.Binding, Kind: kind, Node: node, block: block}
+	r.Definitions = append(r.Definitions, definition)
+	r.events[block] = append(r.events[block], event{definition: definition})
+}
+
+// Bottom returns the empty set
+func (r *ReachingDefinitions) Bottom() Fact {
+	return DefinitionSet{}
+}
+
+// Join returns the union of the sets
+func (r *ReachingDefinitions) Join(a, b Fact) Fact {
+	result := a.(DefinitionSet).copy()
+	for definition := range b.(DefinitionSet) {
+		result[definition] = true
+	}
+
+	return result
+}
+
+// Equal returns true if the sets contain the same definitions
+func (r *ReachingDefinitions) Equal(a, b Fact) bool {
+	x, y := a.(DefinitionSet), b.(DefinitionSet)
+	if len(x) != len(y) {
+		return false
+	}
+	for definition := range x {
+		if !y[definition] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Direction of reaching definitions is forward
+func (r *ReachingDefinitions) Direction() Direction {
+	return Forward
+}
+
+// Boundary returns the definitions at the entry of the function
+func (r *ReachingDefinitions) Boundary(graph *cfg.Graph) Fact {
+	result := DefinitionSet{}
+	for _, definition := range r.entry {
+		result[definition] = true
+	}
+
+	return result
+}
+
+// Transfer kills the definitions of the variables assigned in the block and adds the new ones
+func (r *ReachingDefinitions) Transfer(block *cfg.Block, fact Fact) Fact {
+	result := fact.(DefinitionSet).copy()
+	for _, e := range r.events[block] {
+		if e.definition != nil {
+			result.define(e.definition)
+		}
+	}
+
+	return result
+}
+
+func (s DefinitionSet) copy() DefinitionSet {
+	result := DefinitionSet{}
+	for definition := range s {
+		result[definition] = true
+	}
+
+	return result
+}
+
+// define replaces the definitions of the variable with the given one
+func (s DefinitionSet) define(definition *Definition) {
+	for d := range s {
+		if d.Binding == definition.Binding {
+			delete(s, d)
+		}
+	}
+	s[definition] = true
+}
+
+// UseBeforeAssignment reports the reads reached by the undefined value of a declared variable
+func (r *ReachingDefinitions) UseBeforeAssignment() []*walker.Diagnostic {
+	var diagnostics []*walker.Diagnostic
+	for _, use := range r.Uses {
+		for _, definition := range use.Definitions {
+			if definition.Kind == Declaration {
+				diagnostics = append(diagnostics, r.diagnostic(use.Identifier, UseBeforeAssignmentCode,
+					fmt.Sprintf("%v may be used before it is assigned", use.Binding.Name)))
+				break
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// DeadStores reports the reachable assignments whose value is never read.
+// Variables of the program are global and can be read by other scripts, they are not reported.
+func (r *ReachingDefinitions) DeadStores() []*walker.Diagnostic {
+	if _, ok := r.Graph.Node.(*ast.Program); ok {
+		return nil
+	}
+
+	reachable := r.Graph.Reachable()
+	var diagnostics []*walker.Diagnostic
+	for _, definition := range r.Definitions {
+		if definition.Kind != Assignment || len(definition.Uses) > 0 || !reachable[definition.block] {
+			continue
+		}
+		// Catch parameters and for in variables are assigned without an explicit value
+		switch n := definition.Node.(type) {
+		case *ast.CatchStatement, *ast.Identifier:
+			continue
+		case *ast.VariableExpression:
+			if n.Initializer == nil {
+				continue
+			}
+		}
+		diagnostics = append(diagnostics, r.diagnostic(definition.Node, DeadStoreCode,
+			fmt.Sprintf("%v is assigned a value that is never read", definition.Binding.Name)))
+	}
+
+	return diagnostics
+}
+
+// diagnostic returns a warning located in the walk the references were collected from
+func (r *ReachingDefinitions) diagnostic(node ast.Node, code, message string) *walker.Diagnostic {
+	diagnostic := walker.NewDiagnostic(node, walker.Warning, code, message)
+	diagnostic.Locate(r.metadata)
+
+	return diagnostic
+}
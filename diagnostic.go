@@ -0,0 +1,76 @@
+package walker
+
+import (
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// Severity of a diagnostic
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic describes a problem found in the walked source
+type Diagnostic struct {
+	Node     ast.Node
+	Severity Severity
+	// Code identifies the kind of problem
+	Code    string
+	Message string
+	// Snippet is the rendered source of the node, empty if not rendered
+	Snippet string
+	// Position is the position of the node, nil if not located
+	Position *file.Position
+}
+
+// NewDiagnostic returns a new instance
+func NewDiagnostic(node ast.Node, severity Severity, code, message string) *Diagnostic {
+	return &Diagnostic{
+		Node:     node,
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+	}
+}
+
+// Locate sets the position of the diagnostic from the file and the source map of the metadata,
+// e.g. the current metadata of a hook
+func (d *Diagnostic) Locate(md Metadata) {
+	f, _ := md[FileField].(*file.File)
+	if d.Node == nil || f == nil {
+		return
+	}
+
+	idx0, _ := nodeRange(f, d.Node)
+	d.Position = md.Position(idx0)
+}
+
+// String displays the diagnostic
+func (d *Diagnostic) String() string {
+	text := fmt.Sprintf("%v[%v]: %v", d.Severity, d.Code, d.Message)
+	if d.Position != nil {
+		text = d.Position.String() + ": " + text
+	}
+	if d.Snippet != "" {
+		text += "\n" + d.Snippet
+	}
+
+	return text
+}
@@ -0,0 +1,95 @@
+package walker
+
+import (
+	"github.com/robertkrimen/otto/ast"
+)
+
+// DynamicScopeCode is the code of diagnostics reporting dynamic scopes
+const DynamicScopeCode = "dynamic-scope"
+
+// IsDirectEval returns true if the call is a direct call to eval.
+// The name must resolve to the global eval, calls to a local variable named eval are not direct.
+func IsDirectEval(call *ast.CallExpression, metadata []Metadata) bool {
+	callee, ok := call.Callee.(*ast.Identifier)
+	if !ok || callee.Name != "eval" {
+		return false
+	}
+
+	// A dynamic scope may hide the global eval or not
+	binding := ResolveBinding(metadata, callee.Name)
+	return binding.Scope == nil || binding.Unknown
+}
+
+// ContainsEval returns true if the body of the program or function directly calls eval.
+// Calls in nested functions are not considered. The metadata ends with the metadata of the node.
+func ContainsEval(node ast.Node, metadata []Metadata) bool {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		return containsEval(n.Body, metadata)
+	case *ast.Program:
+		return containsEval(n, metadata)
+	}
+
+	return false
+}
+
+func containsEval(node ast.Node, metadata []Metadata) bool {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		return false
+	case *ast.CallExpression:
+		if IsDirectEval(n, metadata) {
+			return true
+		}
+	case *ast.CatchStatement:
+		// The parameter may shadow eval
+		md := NewMetadata(n)
+		CollectIdentifiers(md, []*ast.Identifier{n.Parameter})
+		metadata = append(metadata[:len(metadata):len(metadata)], md)
+	}
+
+	for _, child := range Children(node) {
+		if containsEval(child, metadata) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DynamicScopeAnalyzer reports with statements and direct calls to eval,
+// which make the variables of their scope unresolvable.
+type DynamicScopeAnalyzer struct {
+	Diagnostics []*Diagnostic
+}
+
+// NewDynamicScopeAnalyzer returns a new instance
+func NewDynamicScopeAnalyzer() *DynamicScopeAnalyzer {
+	return &DynamicScopeAnalyzer{}
+}
+
+// Hook returns the hook performing the analysis
+func (a *DynamicScopeAnalyzer) Hook() *Hook {
+	return &Hook{
+		OnNode: a.onNode,
+	}
+}
+
+func (a *DynamicScopeAnalyzer) onNode(node ast.Node, metadata []Metadata) error {
+	switch n := node.(type) {
+	case *ast.WithStatement:
+		a.report(metadata, n, "with statement makes the scope of its body dynamic")
+	case *ast.CallExpression:
+		if IsDirectEval(n, metadata) {
+			a.report(metadata, n, "direct call to eval makes the enclosing scope dynamic")
+		}
+	}
+
+	return nil
+}
+
+func (a *DynamicScopeAnalyzer) report(metadata []Metadata, node ast.Node, message string) {
+	diagnostic := NewDiagnostic(node, Warning, DynamicScopeCode, message)
+	diagnostic.Locate(CurrentMetadata(metadata))
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
@@ -0,0 +1,65 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+type resolveVisitor struct {
+	VisitorImpl
+	resolved map[string]file.Idx
+}
+
+func (v *resolveVisitor) VisitIdentifier(w *Walker, node *ast.Identifier, metadata []Metadata) Metadata {
+	if IdentifierAccess(metadata) != 0 {
+		v.resolved[node.Name] = FindVariable(metadata, node.Name)
+	}
+
+	return CurrentMetadata(metadata)
+}
+
+func TestDynamicScope(t *testing.T) {
+	tests := []struct {
+		src         string
+		name        string
+		unknown     bool
+		diagnostics int
+	}{
+		{`var a; a`, "a", false, 0},
+		{`var o; with (o) { o }`, "o", true, 1},
+		{`var o, a; with (a) { o }`, "a", false, 1},
+		{`with (o) { (function() { var a; a }) }`, "a", false, 1},
+		{`var a; function f() { eval(s); return a }`, "a", true, 1},
+		{`function f(a) { eval(s); return a }`, "a", false, 1},
+		{`var a; function f() { a; function g() { eval(s) } }`, "a", false, 1},
+		{`var a; a.eval(s)`, "a", false, 0},
+		{`var a; function f(eval) { eval(s); return a }`, "a", false, 0},
+		{`var a, eval; function f() { eval(s); return a }`, "a", false, 0},
+		{`var a; function f() { try {} catch (eval) { eval(s) } return a }`, "a", false, 0},
+		{`var a; function f() { var eval; function g() { eval(s) } return a }`, "a", false, 0},
+		{`var a; function f() { with (o) { eval(s) } return a }`, "a", true, 2},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		analyzer := NewDynamicScopeAnalyzer()
+		visitor := &resolveVisitor{resolved: map[string]file.Idx{}}
+		visitor.AddHook(analyzer.Hook())
+		NewWalker(visitor).Begin(program)
+
+		if unknown := visitor.resolved[test.name] == Unknown; test.unknown != unknown {
+			t.Errorf("[%v] Failed, resolution of %v not correct, %v", i, test.name, visitor.resolved[test.name])
+		}
+		if test.diagnostics != len(analyzer.Diagnostics) {
+			t.Errorf("[%v] Failed, number of diagnostics not correct, %v != %v", i, test.diagnostics, len(analyzer.Diagnostics))
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package walker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// TextEdit replaces the source between Idx0 and Idx1 with Text
+type TextEdit struct {
+	Idx0 file.Idx
+	Idx1 file.Idx
+	Text string
+}
+
+// Offsets returns the byte range of the edit in the source of the given file
+func (e TextEdit) Offsets(f *file.File) (int, int) {
+	return int(e.Idx0) - f.Base(), int(e.Idx1) - f.Base()
+}
+
+// MergeEdits returns the edits sorted by position, with identical replacements removed.
+// Insertions at the same position keep their order and precede a replacement starting there.
+// An error is returned if edits overlap.
+func MergeEdits(edits []TextEdit) ([]TextEdit, error) {
+	var unique []TextEdit
+	seen := map[TextEdit]bool{}
+	for _, edit := range edits {
+		if edit.Idx1 < edit.Idx0 {
+			return nil, fmt.Errorf("Invalid edit, %v is before %v", edit.Idx1, edit.Idx0)
+		}
+		// The same replacement made twice, insertions are repeated
+		if edit.Idx0 != edit.Idx1 && seen[edit] {
+			continue
+		}
+		seen[edit] = true
+		unique = append(unique, edit)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].Idx0 == unique[j].Idx0 {
+			return unique[i].Idx1 < unique[j].Idx1
+		}
+		return unique[i].Idx0 < unique[j].Idx0
+	})
+
+	for i := 1; i < len(unique); i++ {
+		last, edit := unique[i-1], unique[i]
+		if edit.Idx0 < last.Idx1 {
+			return nil, fmt.Errorf("Edit of %v-%v overlaps edit of %v-%v", edit.Idx0, edit.Idx1, last.Idx0, last.Idx1)
+		}
+	}
+
+	return unique, nil
+}
+
+// ApplyEdits applies the edits to the source of the file.
+// It returns the new source and the mapping of the indexes of the file to the new source, which has the same base.
+func ApplyEdits(f *file.File, edits []TextEdit) (string, *PositionMap, error) {
+	merged, err := MergeEdits(edits)
+	if err != nil {
+		return "", nil, err
+	}
+
+	source := f.Source()
+	var builder strings.Builder
+	offset := 0
+	for _, edit := range merged {
+		start, end := edit.Offsets(f)
+		if start < 0 || end > len(source) {
+			return "", nil, fmt.Errorf("Edit of %v-%v is outside of %v", edit.Idx0, edit.Idx1, f.Name())
+		}
+		builder.WriteString(source[offset:start])
+		builder.WriteString(edit.Text)
+		offset = end
+	}
+	builder.WriteString(source[offset:])
+
+	return builder.String(), &PositionMap{edits: merged}, nil
+}
+
+// PositionMap maps the indexes of a source to the source with edits applied, and back
+type PositionMap struct {
+	// edits are sorted and do not overlap
+	edits []TextEdit
+}
+
+// Map returns the index in the new source of the index in the old source.
+// Indexes inside a replaced range are mapped to the start of its replacement, insertions move the indexes they are made at.
+func (m *PositionMap) Map(idx file.Idx) file.Idx {
+	delta := 0
+	for _, edit := range m.edits {
+		switch {
+		case idx >= edit.Idx1:
+			delta += len(edit.Text) - int(edit.Idx1-edit.Idx0)
+		case idx > edit.Idx0:
+			return edit.Idx0 + file.Idx(delta)
+		default:
+			return idx + file.Idx(delta)
+		}
+	}
+
+	return idx + file.Idx(delta)
+}
+
+// Unmap returns the index in the old source of the index in the new source.
+// Indexes inside an inserted text are mapped to the start of the range it replaces.
+func (m *PositionMap) Unmap(idx file.Idx) file.Idx {
+	delta := 0
+	for _, edit := range m.edits {
+		start := edit.Idx0 + file.Idx(delta)
+		end := start + file.Idx(len(edit.Text))
+		switch {
+		case idx >= end:
+			delta += len(edit.Text) - int(edit.Idx1-edit.Idx0)
+		case idx >= start:
+			return edit.Idx0
+		default:
+			return idx - file.Idx(delta)
+		}
+	}
+
+	return idx - file.Idx(delta)
+}
+
+// Editor collects edits of the nodes of a file, e.g. from hooks, and applies them
+type Editor struct {
+	File  *file.File
+	Edits []TextEdit
+
+	spans map[ast.Node][2]int
+}
+
+// NewEditor returns a new instance
+func NewEditor(f *file.File) *Editor {
+	return &Editor{
+		File:  f,
+		spans: map[ast.Node][2]int{},
+	}
+}
+
+// offsets returns the range of the node, including its children, the start of the file if it has none
+func (e *Editor) offsets(node ast.Node) (file.Idx, file.Idx) {
+	s := span(e.File, node, e.spans)
+	if s[0] < 0 {
+		s = [2]int{0, 0}
+	}
+
+	return file.Idx(s[0] + e.File.Base()), file.Idx(s[1] + e.File.Base())
+}
+
+// Replace replaces the source of the node with the text
+func (e *Editor) Replace(node ast.Node, text string) {
+	idx0, idx1 := e.offsets(node)
+	e.Edits = append(e.Edits, TextEdit{Idx0: idx0, Idx1: idx1, Text: text})
+}
+
+// Delete removes the source of the node
+func (e *Editor) Delete(node ast.Node) {
+	e.Replace(node, "")
+}
+
+// InsertBefore inserts the text before the node
+func (e *Editor) InsertBefore(node ast.Node, text string) {
+	idx0, _ := e.offsets(node)
+	e.Edits = append(e.Edits, TextEdit{Idx0: idx0, Idx1: idx0, Text: text})
+}
+
+// InsertAfter inserts the text after the node
+func (e *Editor) InsertAfter(node ast.Node, text string) {
+	_, idx1 := e.offsets(node)
+	e.Edits = append(e.Edits, TextEdit{Idx0: idx1, Idx1: idx1, Text: text})
+}
+
+// Apply applies the collected edits to the file
+func (e *Editor) Apply() (string, *PositionMap, error) {
+	return ApplyEdits(e.File, e.Edits)
+}
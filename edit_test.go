@@ -0,0 +1,182 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestApplyEdits(t *testing.T) {
+	src := "var a = 1; // one\nfoo(a);\n"
+	f := file.NewFile("test.js", src, 1)
+	edit := func(start, end int, text string) TextEdit {
+		return TextEdit{Idx0: file.Idx(start + 1), Idx1: file.Idx(end + 1), Text: text}
+	}
+
+	tests := []struct {
+		edits    []TextEdit
+		expected string
+	}{
+		{nil, src},
+		{[]TextEdit{edit(4, 5, "b"), edit(22, 23, "b")}, "var b = 1; // one\nfoo(b);\n"},
+		{[]TextEdit{edit(22, 23, "b"), edit(4, 5, "b"), edit(4, 5, "b")}, "var b = 1; // one\nfoo(b);\n"},
+		{[]TextEdit{edit(8, 9, "2"), edit(8, 8, "1 + "), edit(18, 18, "bar();\n")}, "var a = 1 + 2; // one\nbar();\nfoo(a);\n"},
+		{[]TextEdit{edit(4, 5, "x"), edit(5, 9, " = 3"), edit(9, 9, "")}, "var x = 3; // one\nfoo(a);\n"},
+		{[]TextEdit{edit(0, 11, ""), edit(11, 18, "")}, "foo(a);\n"},
+		{[]TextEdit{edit(4, 5, "x"), edit(5, 9, " = 3"), edit(5, 9, " = 3")}, "var x = 3; // one\nfoo(a);\n"},
+		{[]TextEdit{edit(8, 8, "1"), edit(8, 8, "1")}, "var a = 111; // one\nfoo(a);\n"},
+		{[]TextEdit{edit(4, 9, "b"), edit(8, 10, "c")}, ""},
+		{[]TextEdit{edit(4, 9, "b"), edit(4, 9, "c")}, ""},
+		{[]TextEdit{edit(5, 4, "b")}, ""},
+		{[]TextEdit{edit(20, 40, "b")}, ""},
+	}
+
+	for i, test := range tests {
+		result, _, err := ApplyEdits(f, test.edits)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("[%v] Failed, expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("[%v] Failed, source not correct, %q != %q", i, test.expected, result)
+		}
+	}
+}
+
+func TestPositionMap(t *testing.T) {
+	// "var a = 1;" becomes "var abc = 1 + 2;"
+	f := file.NewFile("", "var a = 1;", 1)
+	result, m, err := ApplyEdits(f, []TextEdit{
+		{Idx0: 6, Idx1: 6, Text: "bc"},
+		{Idx0: 9, Idx1: 10, Text: "1 + 2"},
+	})
+	if err != nil || result != "var abc = 1 + 2;" {
+		t.Fatalf("Failed, %v %q", err, result)
+	}
+
+	tests := []struct {
+		old, new file.Idx
+	}{
+		{1, 1}, {5, 5}, {6, 8}, {7, 9}, {9, 11}, {10, 16}, {11, 17},
+	}
+	for i, test := range tests {
+		if result := m.Map(test.old); result != test.new {
+			t.Errorf("[%v] Failed, %v mapped to %v, expected %v", i, test.old, result, test.new)
+		}
+	}
+
+	unmapped := []struct {
+		new, old file.Idx
+	}{
+		{5, 5}, {6, 6}, {7, 6}, {8, 6}, {9, 7}, {11, 9}, {13, 9}, {16, 10}, {17, 11},
+	}
+	for i, test := range unmapped {
+		if result := m.Unmap(test.new); result != test.old {
+			t.Errorf("[%v] Failed, %v unmapped to %v, expected %v", i, test.new, result, test.old)
+		}
+	}
+}
+
+func TestPositionMapAdjacentEdits(t *testing.T) {
+	// "abcd" becomes "xyyy", the replacements stay separate
+	f := file.NewFile("", "abcd", 1)
+	result, m, err := ApplyEdits(f, []TextEdit{
+		{Idx0: 3, Idx1: 5, Text: "yyy"},
+		{Idx0: 1, Idx1: 3, Text: "x"},
+		{Idx0: 3, Idx1: 5, Text: "yyy"},
+	})
+	if err != nil || result != "xyyy" {
+		t.Fatalf("Failed, %v %q", err, result)
+	}
+
+	if result := m.Map(4); result != 2 {
+		t.Errorf("Failed, 4 mapped to %v, expected 2", result)
+	}
+	if result := m.Unmap(3); result != 3 {
+		t.Errorf("Failed, 3 unmapped to %v, expected 3", result)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	src := "// Keep this comment\nvar  x = add(1,2);   /* and this */\nlog(x)\n"
+	program, err := parser.ParseFile(nil, "test.js", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	editor := NewEditor(program.File)
+	visitor := &VisitorImpl{}
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			switch n := node.(type) {
+			case *ast.CallExpression:
+				if callee, ok := n.Callee.(*ast.Identifier); ok && callee.Name == "add" {
+					editor.Replace(n, "1 + 2")
+				}
+				if callee, ok := n.Callee.(*ast.Identifier); ok && callee.Name == "log" {
+					editor.Replace(callee, "console.log")
+					editor.InsertAfter(n, ";")
+				}
+			case *ast.ExpressionStatement:
+				editor.InsertBefore(n, "debugger;\n")
+			}
+			return nil
+		},
+	})
+	NewWalker(visitor).Begin(program)
+
+	result, m, err := editor.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "// Keep this comment\nvar  x = 1 + 2;   /* and this */\ndebugger;\nconsole.log(x);\n"
+	if result != expected {
+		t.Errorf("Failed, source not correct\n%v", result)
+	}
+
+	// The argument of log moved by the inserted and replaced text
+	idx := file.Idx(strings.Index(src, "log(x") + 4 + program.File.Base())
+	if moved := int(m.Map(idx)) - program.File.Base(); result[moved:moved+1] != "x" {
+		t.Errorf("Failed, position not mapped, %v", moved)
+	}
+}
+
+func TestEditorEmptyNodes(t *testing.T) {
+	tests := []struct {
+		src      string
+		edit     func(editor *Editor, program *ast.Program)
+		expected string
+	}{
+		{"", func(editor *Editor, program *ast.Program) {
+			editor.InsertBefore(program, "'use strict';\n")
+		}, "'use strict';\n"},
+		{"switch (a) { case 1: case 2: b() }", func(editor *Editor, program *ast.Program) {
+			cases := program.Body[0].(*ast.SwitchStatement).Body
+			editor.Replace(cases[0], "case 0:")
+			editor.Delete(cases[1].Consequent[0])
+		}, "switch (a) { case 0: case 2:  }"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		editor := NewEditor(program.File)
+		test.edit(editor, program)
+		if result, _, err := editor.Apply(); err != nil || result != test.expected {
+			t.Errorf("[%v] Failed, %v, %q != %q", i, err, test.expected, result)
+		}
+	}
+}
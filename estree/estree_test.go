@@ -0,0 +1,211 @@
+package estree
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker/printer"
+)
+
+// texts returns the type and the source of the range of the node and its descendants, sorted
+func texts(n Node, source string) []string {
+	var result []string
+	var visit func(value interface{})
+	visit = func(value interface{}) {
+		switch v := value.(type) {
+		case Node:
+			if start, end := bounds(v); start >= 0 {
+				result = append(result, v.Type()+" "+source[start:end])
+			}
+			for _, field := range v {
+				visit(field)
+			}
+		case []Node:
+			for _, element := range v {
+				visit(element)
+			}
+		case []interface{}:
+			for _, element := range v {
+				visit(element)
+			}
+		}
+	}
+	visit(n)
+	sort.Strings(result)
+
+	return result
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"a = (b + 1) * c;", []string{
+			"AssignmentExpression a = (b + 1) * c", "BinaryExpression (b + 1) * c", "BinaryExpression b + 1",
+			"ExpressionStatement a = (b + 1) * c;", "Identifier a", "Identifier b", "Identifier c", "Literal 1",
+			"Program a = (b + 1) * c;",
+		}},
+		{"var x, y = {a: 1, 'b': [2, , ], get c() { return this }};", []string{
+			"ArrayExpression [2, , ]", "BlockStatement { return this }", "FunctionExpression c() { return this }",
+			"Identifier a", "Identifier c", "Identifier x", "Identifier y", "Literal 'b'", "Literal 1", "Literal 2",
+			"ObjectExpression {a: 1, 'b': [2, , ], get c() { return this }}",
+			"Program var x, y = {a: 1, 'b': [2, , ], get c() { return this }};",
+			"Property 'b': [2, , ]", "Property a: 1", "Property get c() { return this }", "ReturnStatement return this",
+			"ThisExpression this", "VariableDeclaration var x, y = {a: 1, 'b': [2, , ], get c() { return this }};",
+			"VariableDeclarator x", "VariableDeclarator y = {a: 1, 'b': [2, , ], get c() { return this }}",
+		}},
+		{"if (a) b(); else c[0]++", []string{
+			"CallExpression b()", "ExpressionStatement b();", "ExpressionStatement c[0]++", "Identifier a",
+			"Identifier b", "Identifier c", "IfStatement if (a) b(); else c[0]++", "Literal 0", "MemberExpression c[0]",
+			"Program if (a) b(); else c[0]++", "UpdateExpression c[0]++",
+		}},
+		{"for (var i in o) break\nfor (;;) { continue }", []string{
+			"BlockStatement { continue }", "BreakStatement break", "ContinueStatement continue",
+			"ForInStatement for (var i in o) break", "ForStatement for (;;) { continue }", "Identifier i", "Identifier o",
+			"Program for (var i in o) break\nfor (;;) { continue }", "VariableDeclaration var i", "VariableDeclarator i",
+		}},
+		{"do x(); while ((y))\nswitch (z) { case 1: default: }", []string{
+			"CallExpression x()", "DoWhileStatement do x(); while ((y))", "ExpressionStatement x();", "Identifier x",
+			"Identifier y", "Identifier z", "Literal 1", "Program do x(); while ((y))\nswitch (z) { case 1: default: }",
+			"SwitchCase case 1:", "SwitchCase default:", "SwitchStatement switch (z) { case 1: default: }",
+		}},
+		{"l: try { throw e } catch (e) {} finally { debugger; }", []string{
+			"BlockStatement { debugger; }", "BlockStatement { throw e }", "BlockStatement {}",
+			"CatchClause catch (e) {}", "DebuggerStatement debugger;", "Identifier e", "Identifier e", "Identifier l",
+			"LabeledStatement l: try { throw e } catch (e) {} finally { debugger; }",
+			"Program l: try { throw e } catch (e) {} finally { debugger; }", "ThrowStatement throw e",
+			"TryStatement try { throw e } catch (e) {} finally { debugger; }",
+		}},
+		{"(function f(a) { 'use strict'; })(), new F", []string{
+			"BlockStatement { 'use strict'; }", "CallExpression (function f(a) { 'use strict'; })()",
+			"ExpressionStatement 'use strict';", "ExpressionStatement (function f(a) { 'use strict'; })(), new F",
+			"FunctionExpression function f(a) { 'use strict'; }", "Identifier F", "Identifier a", "Identifier f",
+			"Literal 'use strict'", "NewExpression new F", "Program (function f(a) { 'use strict'; })(), new F",
+			"SequenceExpression (function f(a) { 'use strict'; })(), new F",
+		}},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "test.js", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		n, err := Export(program.File, program)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+		if result := texts(n, test.src); strings.Join(result, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("[%v] Failed, ranges not correct\n%q", i, result)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	program, err := parser.ParseFile(nil, "test.js", "'use strict'\nx.y = -1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Marshal(program.File, program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc := func(l0, c0, l1, c1 int) string {
+		return fmt.Sprintf(`"loc":{"source":"test.js","start":{"line":%v,"column":%v},"end":{"line":%v,"column":%v}}`, l0, c0, l1, c1)
+	}
+	expected := `{"body":[` +
+		`{"directive":"use strict","expression":{` + loc(1, 0, 1, 12) + `,"range":[0,12],"raw":"'use strict'","type":"Literal","value":"use strict"},` + loc(1, 0, 1, 12) + `,"range":[0,12],"type":"ExpressionStatement"},` +
+		`{"expression":{"left":{"computed":false,` + loc(2, 0, 2, 3) + `,"object":{` + loc(2, 0, 2, 1) + `,"name":"x","range":[13,14],"type":"Identifier"},"property":{` + loc(2, 2, 2, 3) + `,"name":"y","range":[15,16],"type":"Identifier"},"range":[13,16],"type":"MemberExpression"},` +
+		loc(2, 0, 2, 8) + `,"operator":"=","range":[13,21],"right":{"argument":{` + loc(2, 7, 2, 8) + `,"range":[20,21],"raw":"1","type":"Literal","value":1},` + loc(2, 6, 2, 8) + `,"operator":"-","prefix":true,"range":[19,21],"type":"UnaryExpression"},"type":"AssignmentExpression"},` +
+		loc(2, 0, 2, 8) + `,"range":[13,21],"type":"ExpressionStatement"}],` +
+		loc(1, 0, 2, 8) + `,"range":[0,21],"sourceType":"script","type":"Program"}`
+	if string(data) != expected {
+		t.Errorf("Failed, JSON not correct\n%v\n%v", expected, string(data))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		`var a = 1, b; a += b * (2 + 0x10) / 1.5e3 - -b;`,
+		`function f(x, y) { "use strict"; var z = x ? y : !x; return typeof z === "string" && z.length > 1 || null; }`,
+		`o = {a: [1, , 2], "b c": /x+/gi, 3: true, get d() { return this.a[0]; }, set d(v) {}}; delete o.a; void 0;`,
+		`for (var i = 0, n = 2; i < n; i++) { if (i in o) continue; else break; } for (k in o) ; for (;;) {}`,
+		`outer: while (a) { do { a--; } while (a > 1); switch (a) { case 1: b(); break outer; default: c(); } }`,
+		`try { throw new Error("e"); } catch (e) { debugger; } finally { with (o) { x = new F; } }`,
+		`(function() {})(); a = (b, c); x = f()();`,
+	}
+
+	for i, src := range tests {
+		program, err := parser.ParseFile(nil, "test.js", src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		data, err := Marshal(program.File, program)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+		node, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		if expected, result := printer.String(program), printer.String(node); result != expected {
+			t.Errorf("[%v] Failed, imported program not correct\n%v\n%v", i, expected, result)
+		}
+
+		// Without positions the export of the imported program is the same
+		expected, _ := Marshal(nil, program)
+		if result, err := Marshal(nil, node); err != nil || string(result) != string(expected) {
+			t.Errorf("[%v] Failed, %v\n%s\n%s", i, err, expected, result)
+		}
+		// Positions are kept
+		if result, err := Marshal(program.File, node); err != nil || string(result) != string(data) {
+			t.Errorf("[%v] Failed, positions not kept, %v\n%s\n%s", i, err, data, result)
+		}
+		if len(node.(*ast.Program).DeclarationList) != len(program.DeclarationList) {
+			t.Errorf("[%v] Failed, declarations not correct", i)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []string{
+		`{"type": "ArrowFunctionExpression"}`,
+		`{"type": "BinaryExpression", "operator": "**", "left": {"type": "Identifier", "name": "a"}, "right": {"type": "Identifier", "name": "b"}}`,
+		`{"type": "VariableDeclaration", "kind": "let", "declarations": []}`,
+		`{"type": "ExpressionStatement"}`,
+		`{"type": "Program", "body": [1]}`,
+		`[]`,
+		`null`,
+		`{"type": "FunctionDeclaration", "params": [], "body": {"type": "BlockStatement", "body": []}}`,
+		`{"type": "Literal", "value": null, "regex": "/a/"}`,
+	}
+
+	for i, data := range tests {
+		if _, err := Unmarshal([]byte(data)); err == nil {
+			t.Errorf("[%v] Failed, expected an error", i)
+		}
+	}
+
+	var n Node
+	if err := json.Unmarshal([]byte(`{"type": "Identifier", "name": "a", "start": 4, "end": 5}`), &n); err != nil {
+		t.Fatal(err)
+	}
+	if node, err := Import(n); err != nil || node.Idx0() != 5 {
+		t.Errorf("Failed, start and end not used, %v", err)
+	}
+}
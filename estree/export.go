@@ -0,0 +1,872 @@
+// Package estree converts otto ASTs to ESTree JSON, the AST format of most JavaScript tools, and back.
+package estree
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+// Node is an ESTree node, an object with a type field
+type Node map[string]interface{}
+
+// Type returns the type of the node
+func (n Node) Type() string {
+	t, _ := n["type"].(string)
+	return t
+}
+
+// Position is a 1-based line and a 0-based column, as ESTree locations count them
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// SourceLocation is the location of a node, the end is exclusive
+type SourceLocation struct {
+	Source string   `json:"source,omitempty"`
+	Start  Position `json:"start"`
+	End    Position `json:"end"`
+}
+
+// Export returns the ESTree node of the otto node.
+// If the file is not nil, the nodes have the range of their byte offsets in its source and their location.
+func Export(f *file.File, node ast.Node) (Node, error) {
+	e := &exporter{file: f}
+	if f != nil {
+		e.source = f.Source()
+		e.lines = []int{0}
+		for i := 0; i < len(e.source); i++ {
+			if e.source[i] == '\n' {
+				e.lines = append(e.lines, i+1)
+			}
+		}
+	}
+
+	var n Node
+	switch node := node.(type) {
+	case *ast.Program:
+		n = e.program(node)
+	case ast.Statement:
+		n = e.statement(node)
+	case ast.Expression:
+		n = e.expression(node)
+	default:
+		e.fail(node)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return n, nil
+}
+
+// Marshal returns the ESTree JSON of the otto node
+func Marshal(f *file.File, node ast.Node) ([]byte, error) {
+	n, err := Export(f, node)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(n)
+}
+
+type exporter struct {
+	file   *file.File
+	source string
+	// lines are the offsets of the starts of the lines
+	lines []int
+	err   error
+}
+
+func (e *exporter) fail(node ast.Node) {
+	if e.err == nil {
+		e.err = fmt.Errorf("Cannot export %T", node)
+	}
+}
+
+// offset returns the byte offset of the index, -1 if there is no file
+func (e *exporter) offset(idx file.Idx) int {
+	if e.file == nil {
+		return -1
+	}
+
+	return int(idx) - e.file.Base()
+}
+
+// node returns a node of the type spanning the offsets, which are ignored without a file
+func (e *exporter) node(t string, start, end int) Node {
+	n := Node{"type": t}
+	if e.file == nil || start < 0 {
+		return n
+	}
+
+	start, end = e.clamp(start), e.clamp(end)
+	if end < start {
+		end = start
+	}
+	n["range"] = []int{start, end}
+	n["loc"] = &SourceLocation{
+		Source: e.file.Name(),
+		Start:  e.position(start),
+		End:    e.position(end),
+	}
+
+	return n
+}
+
+func (e *exporter) clamp(offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > len(e.source) {
+		return len(e.source)
+	}
+
+	return offset
+}
+
+// position returns the line and column of the offset
+func (e *exporter) position(offset int) Position {
+	line := sort.Search(len(e.lines), func(i int) bool {
+		return e.lines[i] > offset
+	})
+
+	return Position{Line: line, Column: offset - e.lines[line-1]}
+}
+
+// bounds returns the range of an exported node, -1 and -1 if it has none
+func bounds(n Node) (int, int) {
+	if r, ok := n["range"].([]int); ok {
+		return r[0], r[1]
+	}
+
+	return -1, -1
+}
+
+// start returns the start of the exported node, including the parentheses enclosing it
+func (e *exporter) start(n Node) int {
+	start, _ := e.parenthesized(n)
+	return start
+}
+
+// end returns the end of the exported node, including the parentheses enclosing it
+func (e *exporter) end(n Node) int {
+	_, end := e.parenthesized(n)
+	return end
+}
+
+// parenthesized widens the range of the exported node by the pairs of parentheses enclosing it
+func (e *exporter) parenthesized(n Node) (int, int) {
+	start, end := bounds(n)
+	if start < 0 {
+		return start, end
+	}
+
+	for {
+		before := strings.TrimRight(e.source[:start], " \t\r\n")
+		after := e.skip(end)
+		if !strings.HasSuffix(before, "(") || after >= len(e.source) || e.source[after] != ')' {
+			return start, end
+		}
+		start, end = len(before)-1, after+1
+	}
+}
+
+// skip returns the offset of the next token, skipping whitespace and comments
+func (e *exporter) skip(offset int) int {
+	for offset < len(e.source) {
+		rest := e.source[offset:]
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			next := strings.IndexByte(rest, '\n')
+			if next < 0 {
+				return len(e.source)
+			}
+			offset += next
+		case strings.HasPrefix(rest, "/*"):
+			next := strings.Index(rest[2:], "*/")
+			if next < 0 {
+				return len(e.source)
+			}
+			offset += next + 4
+		case strings.IndexByte(" \t\r\n\v\f", rest[0]) >= 0:
+			offset++
+		default:
+			return offset
+		}
+	}
+
+	return offset
+}
+
+// past returns the offset following the character if it is the next token, else the offset
+func (e *exporter) past(offset int, c byte) int {
+	if offset < 0 {
+		return offset
+	}
+
+	next := e.skip(offset)
+	if next < len(e.source) && e.source[next] == c {
+		return next + 1
+	}
+
+	return offset
+}
+
+// semicolon returns the end of a statement, including a semicolon on the same line
+func (e *exporter) semicolon(end int) int {
+	if end < 0 {
+		return end
+	}
+
+	next := end
+	for next < len(e.source) && (e.source[next] == ' ' || e.source[next] == '\t') {
+		next++
+	}
+	if next < len(e.source) && e.source[next] == ';' {
+		return next + 1
+	}
+
+	return end
+}
+
+func (e *exporter) program(program *ast.Program) Node {
+	n := e.node("Program", 0, len(e.source))
+	n["sourceType"] = "script"
+	n["body"] = e.body(program.Body)
+
+	return n
+}
+
+// body exports the statements of a program or function, marking the directive prologue
+func (e *exporter) body(list []ast.Statement) []Node {
+	nodes := e.statements(list)
+	if e.err != nil {
+		return nodes
+	}
+	for i, statement := range list {
+		expression, ok := statement.(*ast.ExpressionStatement)
+		if !ok {
+			break
+		}
+		literal, ok := expression.Expression.(*ast.StringLiteral)
+		if !ok {
+			break
+		}
+		nodes[i]["directive"] = literal.Literal[1 : len(literal.Literal)-1]
+	}
+
+	return nodes
+}
+
+func (e *exporter) statements(list []ast.Statement) []Node {
+	nodes := []Node{}
+	for _, statement := range list {
+		nodes = append(nodes, e.statement(statement))
+	}
+
+	return nodes
+}
+
+func (e *exporter) expressions(list []ast.Expression) []Node {
+	nodes := []Node{}
+	for _, expression := range list {
+		nodes = append(nodes, e.expression(expression))
+	}
+
+	return nodes
+}
+
+// optional exports the expression, nil if there is none
+func (e *exporter) optional(expression ast.Expression) interface{} {
+	if expression == nil {
+		return nil
+	}
+
+	return e.expression(expression)
+}
+
+func (e *exporter) statement(statement ast.Statement) Node {
+	switch s := statement.(type) {
+	case *ast.BlockStatement:
+		n := e.node("BlockStatement", e.offset(s.LeftBrace), e.offset(s.RightBrace)+1)
+		n["body"] = e.statements(s.List)
+		return n
+	case *ast.BranchStatement:
+		t, end := "BreakStatement", e.offset(s.Idx)+len("break")
+		if s.Token == token.CONTINUE {
+			t, end = "ContinueStatement", e.offset(s.Idx)+len("continue")
+		}
+		var label interface{}
+		if s.Label != nil {
+			l := e.identifier(s.Label)
+			_, end = bounds(l)
+			label = l
+		}
+		n := e.node(t, e.offset(s.Idx), e.semicolon(end))
+		n["label"] = label
+		return n
+	case *ast.DebuggerStatement:
+		return e.node("DebuggerStatement", e.offset(s.Debugger), e.semicolon(e.offset(s.Debugger)+len("debugger")))
+	case *ast.DoWhileStatement:
+		body, test := e.statement(s.Body), e.expression(s.Test)
+		n := e.node("DoWhileStatement", e.keyword("do", s.Do, e.start(body)), e.semicolon(e.past(e.end(test), ')')))
+		n["body"] = body
+		n["test"] = test
+		return n
+	case *ast.EmptyStatement:
+		return e.node("EmptyStatement", e.offset(s.Semicolon), e.offset(s.Semicolon)+1)
+	case *ast.ExpressionStatement:
+		expression := e.expression(s.Expression)
+		n := e.node("ExpressionStatement", e.start(expression), e.semicolon(e.end(expression)))
+		n["expression"] = expression
+		return n
+	case *ast.ForInStatement:
+		start := e.keyword("for", s.For, e.offset(s.Into.Idx0()))
+		var left Node
+		if v, ok := s.Into.(*ast.VariableExpression); ok {
+			left = e.declaration(e.head(start), []*ast.VariableExpression{v}, false)
+		} else {
+			left = e.expression(s.Into)
+		}
+		right, body := e.expression(s.Source), e.statement(s.Body)
+		n := e.node("ForInStatement", start, e.end(body))
+		n["left"] = left
+		n["right"] = right
+		n["body"] = body
+		return n
+	case *ast.ForStatement:
+		test, update, body := e.optional(s.Test), e.optional(s.Update), e.statement(s.Body)
+		before := e.start(body)
+		for _, c := range []interface{}{update, test} {
+			if c, ok := c.(Node); ok {
+				before = e.start(c)
+			}
+		}
+		if sequence, ok := s.Initializer.(*ast.SequenceExpression); ok && len(sequence.Sequence) > 0 {
+			before = e.offset(sequence.Sequence[0].Idx0())
+		}
+		start := e.keyword("for", s.For, before)
+		init := e.initializer(s, start)
+		n := e.node("ForStatement", start, e.end(body))
+		n["init"] = init
+		n["test"] = test
+		n["update"] = update
+		n["body"] = body
+		return n
+	case *ast.FunctionStatement:
+		n := e.function(s.Function)
+		n["type"] = "FunctionDeclaration"
+		return n
+	case *ast.IfStatement:
+		test, consequent := e.expression(s.Test), e.statement(s.Consequent)
+		var alternate interface{}
+		end := e.end(consequent)
+		if s.Alternate != nil {
+			a := e.statement(s.Alternate)
+			end = e.end(a)
+			alternate = a
+		}
+		n := e.node("IfStatement", e.keyword("if", s.If, e.start(test)), end)
+		n["test"] = test
+		n["consequent"] = consequent
+		n["alternate"] = alternate
+		return n
+	case *ast.LabelledStatement:
+		label, body := e.identifier(s.Label), e.statement(s.Statement)
+		n := e.node("LabeledStatement", e.start(label), e.end(body))
+		n["label"] = label
+		n["body"] = body
+		return n
+	case *ast.ReturnStatement:
+		return e.argument("ReturnStatement", s.Return, s.Argument)
+	case *ast.SwitchStatement:
+		discriminant := e.expression(s.Discriminant)
+		cases := []Node{}
+		end := e.past(e.past(e.end(discriminant), ')'), '{')
+		for _, c := range s.Body {
+			n := e.switchCase(c)
+			_, end = bounds(n)
+			cases = append(cases, n)
+		}
+		n := e.node("SwitchStatement", e.keyword("switch", s.Switch, e.start(discriminant)), e.past(end, '}'))
+		n["discriminant"] = discriminant
+		n["cases"] = cases
+		return n
+	case *ast.ThrowStatement:
+		return e.argument("ThrowStatement", s.Throw, s.Argument)
+	case *ast.TryStatement:
+		block := e.statement(s.Body)
+		_, end := bounds(block)
+		var handler, finalizer interface{}
+		if s.Catch != nil {
+			param, body := e.identifier(s.Catch.Parameter), e.statement(s.Catch.Body)
+			_, end = bounds(body)
+			h := e.node("CatchClause", e.offset(s.Catch.Catch), end)
+			h["param"] = param
+			h["body"] = body
+			handler = h
+		}
+		if s.Finally != nil {
+			f := e.statement(s.Finally)
+			_, end = bounds(f)
+			finalizer = f
+		}
+		n := e.node("TryStatement", e.offset(s.Try), end)
+		n["block"] = block
+		n["handler"] = handler
+		n["finalizer"] = finalizer
+		return n
+	case *ast.VariableStatement:
+		var list []*ast.VariableExpression
+		for _, expression := range s.List {
+			v, ok := expression.(*ast.VariableExpression)
+			if !ok {
+				e.fail(expression)
+				return nil
+			}
+			list = append(list, v)
+		}
+		return e.declaration(e.offset(s.Var), list, true)
+	case *ast.WhileStatement:
+		test, body := e.expression(s.Test), e.statement(s.Body)
+		n := e.node("WhileStatement", e.keyword("while", s.While, e.start(test)), e.end(body))
+		n["test"] = test
+		n["body"] = body
+		return n
+	case *ast.WithStatement:
+		object, body := e.expression(s.Object), e.statement(s.Body)
+		n := e.node("WithStatement", e.keyword("with", s.With, e.start(object)), e.end(body))
+		n["object"] = object
+		n["body"] = body
+		return n
+	}
+
+	e.fail(statement)
+	return nil
+}
+
+// argument exports a return or throw statement
+func (e *exporter) argument(t string, idx file.Idx, argument ast.Expression) Node {
+	keyword := strings.ToLower(strings.TrimSuffix(t, "Statement"))
+	start := e.keyword(keyword, idx, e.offset(idx))
+	end := start + len(keyword)
+	var a interface{}
+	if argument != nil {
+		n := e.expression(argument)
+		start = e.keyword(keyword, idx, e.start(n))
+		end = e.end(n)
+		a = n
+	}
+
+	n := e.node(t, start, e.semicolon(end))
+	n["argument"] = a
+	return n
+}
+
+// initializer exports the initializer of a for statement starting at the offset, a sequence of variables or expressions
+func (e *exporter) initializer(s *ast.ForStatement, start int) interface{} {
+	sequence, ok := s.Initializer.(*ast.SequenceExpression)
+	if !ok {
+		return e.optional(s.Initializer)
+	}
+	if len(sequence.Sequence) == 0 {
+		return nil
+	}
+
+	var list []*ast.VariableExpression
+	for _, expression := range sequence.Sequence {
+		if v, ok := expression.(*ast.VariableExpression); ok {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		if len(sequence.Sequence) == 1 {
+			return e.expression(sequence.Sequence[0])
+		}
+		return e.expression(sequence)
+	}
+
+	return e.declaration(e.head(start), list, false)
+}
+
+// head returns the offset of the first token following the parenthesis of a for statement starting at the offset
+func (e *exporter) head(start int) int {
+	if start < 0 {
+		return start
+	}
+
+	return e.skip(e.past(start+len("for"), '('))
+}
+
+// keyword returns the offset of the keyword starting a statement, as the parser does not keep the index of every keyword.
+// The index is used if it is the keyword, else the keyword is searched before the offset of the first child.
+func (e *exporter) keyword(word string, idx file.Idx, before int) int {
+	if e.file == nil {
+		return -1
+	}
+
+	offset := e.offset(idx)
+	if idx > 0 && offset < len(e.source) && strings.HasPrefix(e.source[offset:], word) {
+		return offset
+	}
+	if before < 0 || before > len(e.source) {
+		return -1
+	}
+
+	for {
+		offset = strings.LastIndex(e.source[:before], word)
+		if offset < 0 {
+			return -1
+		}
+		end := offset + len(word)
+		if (offset == 0 || !identifierPart(e.source[offset-1])) && (end == len(e.source) || !identifierPart(e.source[end])) {
+			return offset
+		}
+		before = offset
+	}
+}
+
+func identifierPart(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// declaration exports a variable declaration, the statement form ends with a semicolon
+func (e *exporter) declaration(start int, list []*ast.VariableExpression, statement bool) Node {
+	declarations := []Node{}
+	end := start
+	for _, v := range list {
+		id := e.node("Identifier", e.offset(v.Idx), e.offset(v.Idx)+len(v.Name))
+		id["name"] = v.Name
+		_, end = bounds(id)
+
+		var init interface{}
+		if v.Initializer != nil {
+			i := e.expression(v.Initializer)
+			end = e.end(i)
+			init = i
+		}
+
+		n := e.node("VariableDeclarator", e.offset(v.Idx), end)
+		n["id"] = id
+		n["init"] = init
+		declarations = append(declarations, n)
+	}
+	if statement {
+		end = e.semicolon(end)
+	}
+
+	n := e.node("VariableDeclaration", start, end)
+	n["kind"] = "var"
+	n["declarations"] = declarations
+	return n
+}
+
+func (e *exporter) switchCase(c *ast.CaseStatement) Node {
+	var test interface{}
+	end := e.offset(c.Case) + len("default")
+	if c.Test != nil {
+		t := e.expression(c.Test)
+		end = e.end(t)
+		test = t
+	}
+	end = e.past(end, ':')
+
+	consequent := e.statements(c.Consequent)
+	if len(consequent) > 0 {
+		_, end = bounds(consequent[len(consequent)-1])
+	}
+
+	n := e.node("SwitchCase", e.offset(c.Case), end)
+	n["test"] = test
+	n["consequent"] = consequent
+	return n
+}
+
+func (e *exporter) identifier(identifier *ast.Identifier) Node {
+	n := e.node("Identifier", e.offset(identifier.Idx), e.offset(identifier.Idx)+len(identifier.Name))
+	n["name"] = identifier.Name
+	return n
+}
+
+// function exports a function expression, the caller changes the type of declarations
+func (e *exporter) function(function *ast.FunctionLiteral) Node {
+	var id interface{}
+	if function.Name != nil {
+		id = e.identifier(function.Name)
+	}
+	params := []Node{}
+	if function.ParameterList != nil {
+		for _, parameter := range function.ParameterList.List {
+			params = append(params, e.identifier(parameter))
+		}
+	}
+	block, ok := function.Body.(*ast.BlockStatement)
+	if !ok {
+		e.fail(function.Body)
+		return nil
+	}
+	body := e.node("BlockStatement", e.offset(block.LeftBrace), e.offset(block.RightBrace)+1)
+	body["body"] = e.body(block.List)
+
+	_, end := bounds(body)
+	n := e.node("FunctionExpression", e.offset(function.Function), end)
+	n["id"] = id
+	n["params"] = params
+	n["body"] = body
+	n["generator"] = false
+	n["expression"] = false
+	return n
+}
+
+// literal returns a literal node of the value and its source
+func (e *exporter) literal(idx file.Idx, raw string, value interface{}) Node {
+	n := e.node("Literal", e.offset(idx), e.offset(idx)+len(raw))
+	n["value"] = value
+	n["raw"] = raw
+	return n
+}
+
+func (e *exporter) expression(expression ast.Expression) Node {
+	switch x := expression.(type) {
+	case *ast.ArrayLiteral:
+		elements := []interface{}{}
+		for _, value := range x.Value {
+			if _, hole := value.(*ast.EmptyExpression); hole {
+				elements = append(elements, nil)
+				continue
+			}
+			elements = append(elements, e.expression(value))
+		}
+		n := e.node("ArrayExpression", e.offset(x.LeftBracket), e.offset(x.RightBracket)+1)
+		n["elements"] = elements
+		return n
+	case *ast.AssignExpression:
+		operator := "="
+		if x.Operator != token.ASSIGN {
+			operator = x.Operator.String() + "="
+		}
+		left, right := e.expression(x.Left), e.expression(x.Right)
+		n := e.node("AssignmentExpression", e.start(left), e.end(right))
+		n["operator"] = operator
+		n["left"] = left
+		n["right"] = right
+		return n
+	case *ast.BinaryExpression:
+		t := "BinaryExpression"
+		if x.Operator == token.LOGICAL_AND || x.Operator == token.LOGICAL_OR {
+			t = "LogicalExpression"
+		}
+		left, right := e.expression(x.Left), e.expression(x.Right)
+		n := e.node(t, e.start(left), e.end(right))
+		n["operator"] = x.Operator.String()
+		n["left"] = left
+		n["right"] = right
+		return n
+	case *ast.BooleanLiteral:
+		return e.literal(x.Idx, x.Literal, x.Value)
+	case *ast.BracketExpression:
+		object, property := e.expression(x.Left), e.expression(x.Member)
+		n := e.node("MemberExpression", e.start(object), e.offset(x.RightBracket)+1)
+		n["object"] = object
+		n["property"] = property
+		n["computed"] = true
+		return n
+	case *ast.CallExpression:
+		callee, arguments := e.expression(x.Callee), e.expressions(x.ArgumentList)
+		n := e.node("CallExpression", e.start(callee), e.offset(x.RightParenthesis)+1)
+		n["callee"] = callee
+		n["arguments"] = arguments
+		return n
+	case *ast.ConditionalExpression:
+		test, consequent, alternate := e.expression(x.Test), e.expression(x.Consequent), e.expression(x.Alternate)
+		n := e.node("ConditionalExpression", e.start(test), e.end(alternate))
+		n["test"] = test
+		n["consequent"] = consequent
+		n["alternate"] = alternate
+		return n
+	case *ast.DotExpression:
+		object, property := e.expression(x.Left), e.identifier(x.Identifier)
+		_, end := bounds(property)
+		n := e.node("MemberExpression", e.start(object), end)
+		n["object"] = object
+		n["property"] = property
+		n["computed"] = false
+		return n
+	case *ast.FunctionLiteral:
+		return e.function(x)
+	case *ast.Identifier:
+		return e.identifier(x)
+	case *ast.NewExpression:
+		callee, arguments := e.expression(x.Callee), e.expressions(x.ArgumentList)
+		end := e.end(callee)
+		if x.RightParenthesis > 0 {
+			end = e.offset(x.RightParenthesis) + 1
+		}
+		n := e.node("NewExpression", e.offset(x.New), end)
+		n["callee"] = callee
+		n["arguments"] = arguments
+		return n
+	case *ast.NullLiteral:
+		return e.literal(x.Idx, "null", nil)
+	case *ast.NumberLiteral:
+		return e.literal(x.Idx, x.Literal, x.Value)
+	case *ast.ObjectLiteral:
+		properties := []Node{}
+		after := e.offset(x.LeftBrace) + 1
+		for _, property := range x.Value {
+			n := e.property(after, property)
+			_, after = bounds(n)
+			properties = append(properties, n)
+		}
+		n := e.node("ObjectExpression", e.offset(x.LeftBrace), e.offset(x.RightBrace)+1)
+		n["properties"] = properties
+		return n
+	case *ast.RegExpLiteral:
+		n := e.literal(x.Idx, x.Literal, nil)
+		n["regex"] = map[string]string{"pattern": x.Pattern, "flags": x.Flags}
+		return n
+	case *ast.SequenceExpression:
+		expressions := e.expressions(x.Sequence)
+		if len(expressions) == 0 {
+			e.fail(x)
+			return nil
+		}
+		n := e.node("SequenceExpression", e.start(expressions[0]), e.end(expressions[len(expressions)-1]))
+		n["expressions"] = expressions
+		return n
+	case *ast.StringLiteral:
+		return e.literal(x.Idx, x.Literal, x.Value)
+	case *ast.ThisExpression:
+		return e.node("ThisExpression", e.offset(x.Idx), e.offset(x.Idx)+len("this"))
+	case *ast.UnaryExpression:
+		t := "UnaryExpression"
+		if x.Operator == token.INCREMENT || x.Operator == token.DECREMENT {
+			t = "UpdateExpression"
+		}
+		argument := e.expression(x.Operand)
+		start, end := e.offset(x.Idx), e.end(argument)
+		if x.Postfix {
+			start, end = e.start(argument), e.offset(x.Idx)+2
+		}
+		n := e.node(t, start, end)
+		n["operator"] = x.Operator.String()
+		n["prefix"] = !x.Postfix
+		n["argument"] = argument
+		return n
+	}
+
+	e.fail(expression)
+	return nil
+}
+
+// property exports the property of an object literal, its key is found in the source following the offset
+func (e *exporter) property(after int, property ast.Property) Node {
+	value := e.expression(property.Value)
+	if e.err != nil {
+		return nil
+	}
+
+	kind := property.Kind
+	if kind == "value" {
+		kind = "init"
+	}
+
+	var key Node
+	start, end := -1, e.end(value)
+	if e.file != nil {
+		start = e.skip(e.past(after, ','))
+		keyStart := start
+		if kind != "init" {
+			keyStart = e.skip(start + len(kind))
+		}
+		key = e.key(keyStart, property.Key)
+	} else {
+		key = e.key(-1, property.Key)
+	}
+
+	n := e.node("Property", start, end)
+	n["key"] = key
+	n["value"] = value
+	n["kind"] = kind
+	n["computed"] = false
+	n["method"] = false
+	n["shorthand"] = false
+	return n
+}
+
+// key exports the key of a property starting at the offset, an identifier or a string or number literal
+func (e *exporter) key(start int, name string) Node {
+	raw := name
+	switch {
+	case start >= 0 && start < len(e.source):
+		end := start + 1
+		if quote := e.source[start]; quote == '"' || quote == '\'' {
+			for end < len(e.source) && e.source[end] != quote {
+				if e.source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+		} else {
+			for end < len(e.source) && strings.IndexByte(" \t\r\n:(/", e.source[end]) < 0 {
+				end++
+			}
+		}
+		raw = e.source[start:e.clamp(end)]
+	case !identifierName(name):
+		if _, err := number(name); err != nil {
+			raw = strconv.Quote(name)
+		}
+	}
+
+	if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+		n := e.node("Literal", start, start+len(raw))
+		n["value"] = name
+		n["raw"] = raw
+		return n
+	}
+	if value, err := number(raw); err == nil {
+		n := e.node("Literal", start, start+len(raw))
+		n["value"] = value
+		n["raw"] = raw
+		return n
+	}
+
+	n := e.node("Identifier", start, start+len(raw))
+	n["name"] = name
+	return n
+}
+
+// identifierName returns true if the name is an identifier name
+func identifierName(name string) bool {
+	for i, r := range name {
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return name != ""
+}
+
+// number returns the value of a numeric literal
+func number(raw string) (float64, error) {
+	if raw == "" || raw[0] != '.' && (raw[0] < '0' || raw[0] > '9') {
+		return 0, fmt.Errorf("Invalid number %q", raw)
+	}
+	if len(raw) > 2 && (raw[:2] == "0x" || raw[:2] == "0X") {
+		value, err := strconv.ParseUint(raw[2:], 16, 64)
+		return float64(value), err
+	}
+
+	return strconv.ParseFloat(raw, 64)
+}
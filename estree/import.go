@@ -0,0 +1,587 @@
+package estree
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/robertkrimen/otto/token"
+)
+
+// operators maps the operators of ESTree nodes to their tokens
+var operators = func() map[string]token.Token {
+	m := map[string]token.Token{}
+	for t := token.Token(1); !strings.HasPrefix(t.String(), "token("); t++ {
+		m[t.String()] = t
+	}
+
+	return m
+}()
+
+// Unmarshal returns the otto node of the ESTree JSON, see Import
+func Unmarshal(data []byte) (ast.Node, error) {
+	var n Node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+
+	return Import(n)
+}
+
+// Import returns the otto node of the ESTree node, which is either a program, a statement or an expression.
+// The indexes of the nodes are the offsets of their ranges plus 1, the base of a file parsed on its own,
+// and 0 for nodes without a range. Only the nodes of ECMAScript 5 are supported.
+func Import(n Node) (ast.Node, error) {
+	if n == nil {
+		return nil, fmt.Errorf("Cannot import a missing node")
+	}
+	i := &importer{}
+
+	var node ast.Node
+	switch {
+	case n.Type() == "Program":
+		node = i.program(n)
+	case statementTypes[n.Type()]:
+		node = i.statement(n)
+	default:
+		node = i.expression(n)
+	}
+	if i.err != nil {
+		return nil, i.err
+	}
+
+	return node, nil
+}
+
+var statementTypes = map[string]bool{
+	"BlockStatement": true, "BreakStatement": true, "ContinueStatement": true, "DebuggerStatement": true,
+	"DoWhileStatement": true, "EmptyStatement": true, "ExpressionStatement": true, "ForInStatement": true,
+	"ForStatement": true, "FunctionDeclaration": true, "IfStatement": true, "LabeledStatement": true,
+	"ReturnStatement": true, "SwitchStatement": true, "ThrowStatement": true, "TryStatement": true,
+	"VariableDeclaration": true, "WhileStatement": true, "WithStatement": true,
+}
+
+type importer struct {
+	// declarations are the declarations of the current function or program
+	declarations *[]ast.Declaration
+	err          error
+}
+
+func (i *importer) fail(format string, args ...interface{}) {
+	if i.err == nil {
+		i.err = fmt.Errorf(format, args...)
+	}
+}
+
+// object returns the node of a field value, nil if it is not an object
+func object(value interface{}) Node {
+	switch v := value.(type) {
+	case Node:
+		return v
+	case map[string]interface{}:
+		return Node(v)
+	}
+
+	return nil
+}
+
+// list returns the elements of a field value
+func list(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []Node:
+		var values []interface{}
+		for _, n := range v {
+			values = append(values, n)
+		}
+		return values
+	}
+
+	return nil
+}
+
+// child returns the node of the field, failing if it is missing
+func (i *importer) child(n Node, field string) Node {
+	c := object(n[field])
+	if c == nil {
+		i.fail("Invalid %v, %v is missing", n.Type(), field)
+	}
+
+	return c
+}
+
+// offsets returns the range of the node, from the range field or the start and end fields
+func offsets(n Node) (int, int, bool) {
+	r := list(n["range"])
+	if ints, ok := n["range"].([]int); ok {
+		r = []interface{}{ints[0], ints[1]}
+	}
+	if len(r) != 2 {
+		r = []interface{}{n["start"], n["end"]}
+	}
+
+	start, ok0 := integer(r[0])
+	end, ok1 := integer(r[1])
+	return start, end, ok0 && ok1
+}
+
+func integer(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+
+	return 0, false
+}
+
+// idx returns the index of the start of the node
+func idx(n Node) file.Idx {
+	if start, _, ok := offsets(n); ok {
+		return file.Idx(start + 1)
+	}
+
+	return 0
+}
+
+// last returns the index of the last character of the node, e.g. a closing brace
+func last(n Node) file.Idx {
+	if _, end, ok := offsets(n); ok && end > 0 {
+		return file.Idx(end)
+	}
+
+	return 0
+}
+
+// declare adds the declaration to the current function or program
+func (i *importer) declare(declaration ast.Declaration) {
+	if i.declarations != nil {
+		*i.declarations = append(*i.declarations, declaration)
+	}
+}
+
+func (i *importer) program(n Node) *ast.Program {
+	program := &ast.Program{}
+	i.declarations = &program.DeclarationList
+	program.Body = i.statements(n["body"])
+
+	return program
+}
+
+func (i *importer) statements(value interface{}) []ast.Statement {
+	var statements []ast.Statement
+	for _, element := range list(value) {
+		n := object(element)
+		if n == nil {
+			i.fail("Invalid statement %v", element)
+			return nil
+		}
+		statements = append(statements, i.statement(n))
+	}
+
+	return statements
+}
+
+func (i *importer) expressions(value interface{}) []ast.Expression {
+	var expressions []ast.Expression
+	for _, element := range list(value) {
+		n := object(element)
+		if n == nil {
+			i.fail("Invalid expression %v", element)
+			return nil
+		}
+		expressions = append(expressions, i.expression(n))
+	}
+
+	return expressions
+}
+
+// optional imports the expression of the field, nil if it is null
+func (i *importer) optional(n Node, field string) ast.Expression {
+	if c := object(n[field]); c != nil {
+		return i.expression(c)
+	}
+
+	return nil
+}
+
+// optionalStatement imports the statement of the field, nil if it is null
+func (i *importer) optionalStatement(n Node, field string) ast.Statement {
+	if c := object(n[field]); c != nil {
+		return i.statement(c)
+	}
+
+	return nil
+}
+
+func (i *importer) statement(n Node) ast.Statement {
+	if n == nil {
+		return nil
+	}
+
+	switch n.Type() {
+	case "BlockStatement":
+		return &ast.BlockStatement{LeftBrace: idx(n), List: i.statements(n["body"]), RightBrace: last(n)}
+	case "BreakStatement", "ContinueStatement":
+		s := &ast.BranchStatement{Idx: idx(n), Token: token.BREAK}
+		if n.Type() == "ContinueStatement" {
+			s.Token = token.CONTINUE
+		}
+		if label := object(n["label"]); label != nil {
+			s.Label = i.identifier(label)
+		}
+		return s
+	case "DebuggerStatement":
+		return &ast.DebuggerStatement{Debugger: idx(n)}
+	case "DoWhileStatement":
+		return &ast.DoWhileStatement{Do: idx(n), Body: i.statement(i.child(n, "body")), Test: i.expression(i.child(n, "test"))}
+	case "EmptyStatement":
+		return &ast.EmptyStatement{Semicolon: idx(n)}
+	case "ExpressionStatement":
+		return &ast.ExpressionStatement{Expression: i.expression(i.child(n, "expression"))}
+	case "ForInStatement":
+		s := &ast.ForInStatement{For: idx(n)}
+		if left := i.child(n, "left"); left.Type() == "VariableDeclaration" {
+			list := i.declaration(left)
+			if len(list) != 1 {
+				i.fail("Invalid ForInStatement, %v variables are declared", len(list))
+				return nil
+			}
+			s.Into = list[0]
+		} else {
+			s.Into = i.expression(left)
+		}
+		s.Source = i.expression(i.child(n, "right"))
+		s.Body = i.statement(i.child(n, "body"))
+		return s
+	case "ForStatement":
+		// The parser keeps the initializer in a sequence, which is empty if there is none
+		initializer := &ast.SequenceExpression{}
+		if init := object(n["init"]); init != nil && init.Type() == "VariableDeclaration" {
+			for _, v := range i.declaration(init) {
+				initializer.Sequence = append(initializer.Sequence, v)
+			}
+		} else if init != nil {
+			initializer.Sequence = []ast.Expression{i.expression(init)}
+		}
+		return &ast.ForStatement{
+			For:         idx(n),
+			Initializer: initializer,
+			Test:        i.optional(n, "test"),
+			Update:      i.optional(n, "update"),
+			Body:        i.statement(i.child(n, "body")),
+		}
+	case "FunctionDeclaration":
+		// Only function expressions may be anonymous
+		i.child(n, "id")
+		return &ast.FunctionStatement{Function: i.function(n, true)}
+	case "IfStatement":
+		return &ast.IfStatement{
+			If:         idx(n),
+			Test:       i.expression(i.child(n, "test")),
+			Consequent: i.statement(i.child(n, "consequent")),
+			Alternate:  i.optionalStatement(n, "alternate"),
+		}
+	case "LabeledStatement":
+		label := i.identifier(i.child(n, "label"))
+		s := &ast.LabelledStatement{Label: label, Statement: i.statement(i.child(n, "body"))}
+		if label != nil && label.Idx > 0 {
+			s.Colon = label.Idx1()
+		}
+		return s
+	case "ReturnStatement":
+		return &ast.ReturnStatement{Return: idx(n), Argument: i.optional(n, "argument")}
+	case "SwitchStatement":
+		s := &ast.SwitchStatement{Switch: idx(n), Discriminant: i.expression(i.child(n, "discriminant")), Default: -1}
+		for index, element := range list(n["cases"]) {
+			c := object(element)
+			if c == nil || c.Type() != "SwitchCase" {
+				i.fail("Invalid case %v", element)
+				return nil
+			}
+			test := i.optional(c, "test")
+			if test == nil {
+				s.Default = index
+			}
+			s.Body = append(s.Body, &ast.CaseStatement{Case: idx(c), Test: test, Consequent: i.statements(c["consequent"])})
+		}
+		return s
+	case "ThrowStatement":
+		return &ast.ThrowStatement{Throw: idx(n), Argument: i.expression(i.child(n, "argument"))}
+	case "TryStatement":
+		s := &ast.TryStatement{Try: idx(n), Body: i.statement(i.child(n, "block")), Finally: i.optionalStatement(n, "finalizer")}
+		if handler := object(n["handler"]); handler != nil {
+			s.Catch = &ast.CatchStatement{
+				Catch:     idx(handler),
+				Parameter: i.identifier(i.child(handler, "param")),
+				Body:      i.statement(i.child(handler, "body")),
+			}
+		}
+		return s
+	case "VariableDeclaration":
+		s := &ast.VariableStatement{Var: idx(n)}
+		for _, v := range i.declaration(n) {
+			s.List = append(s.List, v)
+		}
+		return s
+	case "WhileStatement":
+		return &ast.WhileStatement{While: idx(n), Test: i.expression(i.child(n, "test")), Body: i.statement(i.child(n, "body"))}
+	case "WithStatement":
+		return &ast.WithStatement{With: idx(n), Object: i.expression(i.child(n, "object")), Body: i.statement(i.child(n, "body"))}
+	}
+
+	i.fail("Cannot import %v", n.Type())
+	return nil
+}
+
+// declaration imports the variables of the declaration and declares them
+func (i *importer) declaration(n Node) []*ast.VariableExpression {
+	if kind, _ := n["kind"].(string); kind != "var" {
+		i.fail("Cannot import %v declarations", kind)
+		return nil
+	}
+
+	var variables []*ast.VariableExpression
+	for _, element := range list(n["declarations"]) {
+		declarator := object(element)
+		if declarator == nil || declarator.Type() != "VariableDeclarator" {
+			i.fail("Invalid declarator %v", element)
+			return nil
+		}
+		id := i.identifier(i.child(declarator, "id"))
+		if id == nil {
+			return nil
+		}
+		variables = append(variables, &ast.VariableExpression{Name: id.Name, Idx: id.Idx, Initializer: i.optional(declarator, "init")})
+	}
+	i.declare(&ast.VariableDeclaration{Var: idx(n), List: variables})
+
+	return variables
+}
+
+func (i *importer) identifier(n Node) *ast.Identifier {
+	if n == nil || n.Type() != "Identifier" {
+		i.fail("Invalid identifier %v", n)
+		return nil
+	}
+
+	name, _ := n["name"].(string)
+	return &ast.Identifier{Name: name, Idx: idx(n)}
+}
+
+// function imports a function expression or declaration, which is declared in the enclosing function
+func (i *importer) function(n Node, declaration bool) *ast.FunctionLiteral {
+	function := &ast.FunctionLiteral{Function: idx(n), ParameterList: &ast.ParameterList{}}
+	if id := object(n["id"]); id != nil {
+		function.Name = i.identifier(id)
+	}
+	if declaration {
+		i.declare(&ast.FunctionDeclaration{Function: function})
+	}
+	for _, element := range list(n["params"]) {
+		function.ParameterList.List = append(function.ParameterList.List, i.identifier(object(element)))
+	}
+
+	outer := i.declarations
+	i.declarations = &function.DeclarationList
+	body := i.child(n, "body")
+	if body != nil && body.Type() != "BlockStatement" {
+		i.fail("Cannot import functions with expression bodies")
+	}
+	function.Body = i.statement(body)
+	i.declarations = outer
+
+	return function
+}
+
+// operator returns the token of the operator of the node
+func (i *importer) operator(n Node) token.Token {
+	operator, _ := n["operator"].(string)
+	t, ok := operators[operator]
+	if !ok {
+		i.fail("Unknown operator %q", operator)
+	}
+
+	return t
+}
+
+func (i *importer) expression(n Node) ast.Expression {
+	if n == nil {
+		return nil
+	}
+
+	switch n.Type() {
+	case "ArrayExpression":
+		e := &ast.ArrayLiteral{LeftBracket: idx(n), RightBracket: last(n)}
+		for _, element := range list(n["elements"]) {
+			if element == nil {
+				e.Value = append(e.Value, &ast.EmptyExpression{})
+				continue
+			}
+			e.Value = append(e.Value, i.expression(object(element)))
+		}
+		return e
+	case "AssignmentExpression":
+		e := &ast.AssignExpression{Operator: token.ASSIGN, Left: i.expression(i.child(n, "left")), Right: i.expression(i.child(n, "right"))}
+		if operator, _ := n["operator"].(string); operator != "=" {
+			e.Operator = i.operator(Node{"operator": strings.TrimSuffix(operator, "=")})
+		}
+		return e
+	case "BinaryExpression", "LogicalExpression":
+		e := &ast.BinaryExpression{Operator: i.operator(n), Left: i.expression(i.child(n, "left")), Right: i.expression(i.child(n, "right"))}
+		switch e.Operator {
+		case token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL,
+			token.LESS, token.LESS_OR_EQUAL, token.GREATER, token.GREATER_OR_EQUAL:
+			e.Comparison = true
+		}
+		return e
+	case "CallExpression":
+		return &ast.CallExpression{Callee: i.expression(i.child(n, "callee")), ArgumentList: i.expressions(n["arguments"]), RightParenthesis: last(n)}
+	case "ConditionalExpression":
+		return &ast.ConditionalExpression{
+			Test:       i.expression(i.child(n, "test")),
+			Consequent: i.expression(i.child(n, "consequent")),
+			Alternate:  i.expression(i.child(n, "alternate")),
+		}
+	case "FunctionExpression":
+		return i.function(n, false)
+	case "Identifier":
+		return i.identifier(n)
+	case "Literal":
+		return i.literal(n)
+	case "MemberExpression":
+		left := i.expression(i.child(n, "object"))
+		if computed, _ := n["computed"].(bool); computed {
+			return &ast.BracketExpression{Left: left, Member: i.expression(i.child(n, "property")), RightBracket: last(n)}
+		}
+		return &ast.DotExpression{Left: left, Identifier: i.identifier(i.child(n, "property"))}
+	case "NewExpression":
+		return &ast.NewExpression{New: idx(n), Callee: i.expression(i.child(n, "callee")), ArgumentList: i.expressions(n["arguments"]), RightParenthesis: last(n)}
+	case "ObjectExpression":
+		e := &ast.ObjectLiteral{LeftBrace: idx(n), RightBrace: last(n)}
+		for _, element := range list(n["properties"]) {
+			e.Value = append(e.Value, i.property(object(element)))
+		}
+		return e
+	case "SequenceExpression":
+		return &ast.SequenceExpression{Sequence: i.expressions(n["expressions"])}
+	case "ThisExpression":
+		return &ast.ThisExpression{Idx: idx(n)}
+	case "UnaryExpression", "UpdateExpression":
+		e := &ast.UnaryExpression{Operator: i.operator(n), Idx: idx(n), Operand: i.expression(i.child(n, "argument"))}
+		if prefix, ok := n["prefix"].(bool); ok && !prefix {
+			// The index of postfix operations is the operator
+			e.Postfix = true
+			if e.Idx > 0 {
+				e.Idx = last(n) - 1
+			}
+		}
+		return e
+	}
+
+	i.fail("Cannot import %v", n.Type())
+	return nil
+}
+
+// property imports the property of an object expression
+func (i *importer) property(n Node) ast.Property {
+	if n == nil || n.Type() != "Property" {
+		i.fail("Invalid property %v", n)
+		return ast.Property{}
+	}
+
+	property := ast.Property{Kind: "value", Value: i.expression(i.child(n, "value"))}
+	if kind, _ := n["kind"].(string); kind == "get" || kind == "set" {
+		property.Kind = kind
+	}
+
+	key := i.child(n, "key")
+	switch key.Type() {
+	case "Identifier":
+		property.Key, _ = key["name"].(string)
+	case "Literal":
+		if raw, ok := key["raw"].(string); ok && (raw == "" || raw[0] != '"' && raw[0] != '\'') {
+			// The key of a number is its literal
+			property.Key = raw
+		} else if value, ok := numeric(key["value"]); ok {
+			property.Key = strconv.FormatFloat(value, 'g', -1, 64)
+		} else {
+			property.Key = fmt.Sprint(key["value"])
+		}
+	default:
+		i.fail("Invalid property key %v", key.Type())
+	}
+
+	return property
+}
+
+// numeric returns the value of a number field
+func numeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+func (i *importer) literal(n Node) ast.Expression {
+	raw, _ := n["raw"].(string)
+	if value, ok := numeric(n["value"]); ok {
+		if raw == "" {
+			raw = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		e := &ast.NumberLiteral{Idx: idx(n), Literal: raw, Value: value}
+		// The parser keeps integers as int64
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 && !strings.ContainsAny(raw, ".eE") || strings.HasPrefix(raw, "0x") {
+			e.Value = int64(value)
+		}
+		return e
+	}
+
+	switch value := n["value"].(type) {
+	case string:
+		if raw == "" {
+			raw = strconv.Quote(value)
+		}
+		return &ast.StringLiteral{Idx: idx(n), Literal: raw, Value: value}
+	case bool:
+		return &ast.BooleanLiteral{Idx: idx(n), Literal: strconv.FormatBool(value), Value: value}
+	case nil:
+		if n["regex"] == nil {
+			return &ast.NullLiteral{Idx: idx(n), Literal: "null"}
+		}
+		regex := object(n["regex"])
+		if r, ok := n["regex"].(map[string]string); ok {
+			regex = Node{"pattern": r["pattern"], "flags": r["flags"]}
+		}
+		if regex == nil {
+			i.fail("Invalid Literal, regex %v is not an object", n["regex"])
+			return nil
+		}
+
+		e := &ast.RegExpLiteral{Idx: idx(n), Literal: raw}
+		e.Pattern, _ = regex["pattern"].(string)
+		e.Flags, _ = regex["flags"].(string)
+		if e.Literal == "" {
+			e.Literal = "/" + e.Pattern + "/" + e.Flags
+		}
+		// The value is the pattern in Go syntax, empty if it can not be transformed
+		e.Value, _ = parser.TransformRegExp(e.Pattern)
+		return e
+	}
+
+	i.fail("Invalid literal %v", n["value"])
+	return nil
+}
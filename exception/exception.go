@@ -0,0 +1,233 @@
+// Package exception analyzes which functions may throw exceptions their callers have to handle.
+package exception
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+	"github.com/wolfgarnet/walker/callgraph"
+)
+
+const (
+	// SummaryField is the metadata field of functions and programs holding their summary
+	SummaryField = "exceptions"
+
+	// ThrowNonErrorCode is the code of diagnostics reporting thrown values that are not errors
+	ThrowNonErrorCode = "throw-non-error"
+	// EmptyCatchCode is the code of diagnostics reporting catch blocks ignoring the error
+	EmptyCatchCode = "empty-catch"
+)
+
+// Summary describes the exceptions escaping a function, or the top level code of a program
+type Summary struct {
+	// Node is the *ast.FunctionLiteral or *ast.Program
+	Node ast.Node
+	// Throws lists the throw statements not caught in the function
+	Throws []*ast.ThrowStatement
+	// Calls lists the calls not caught in the function, of functions that may throw
+	Calls []ast.Expression
+	// MayThrow is true if an exception may escape the function
+	MayThrow bool
+
+	// sites lists the calls not caught in the function
+	sites []ast.Expression
+}
+
+// Analysis holds the summaries of the functions of one or more programs
+type Analysis struct {
+	Summaries   map[ast.Node]*Summary
+	Diagnostics []*walker.Diagnostic
+}
+
+// Analyze returns the summaries of the programs and their functions.
+// Calls are resolved through the call graph of all the programs.
+func Analyze(programs ...*ast.Program) *Analysis {
+	a := &Analysis{
+		Summaries: map[ast.Node]*Summary{},
+	}
+
+	for _, program := range programs {
+		visitor := &walker.VisitorImpl{}
+		visitor.AddHook(&walker.Hook{
+			OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+				a.onNode(program, node, metadata)
+				return nil
+			},
+		})
+		walker.NewWalker(visitor).Begin(program)
+	}
+
+	a.propagate(callgraph.Build(programs...))
+
+	return a
+}
+
+// summary returns the summary of the node, creating it if needed
+func (a *Analysis) summary(node ast.Node) *Summary {
+	summary, ok := a.Summaries[node]
+	if !ok {
+		summary = &Summary{Node: node}
+		a.Summaries[node] = summary
+	}
+
+	return summary
+}
+
+func (a *Analysis) onNode(program *ast.Program, node ast.Node, metadata []walker.Metadata) {
+	var function ast.Node = program
+	if literal := walker.FindParentFunction(metadata); literal != nil {
+		function = literal
+	}
+
+	switch n := node.(type) {
+	case *ast.Program, *ast.FunctionLiteral:
+		a.summary(n)
+	case *ast.ThrowStatement:
+		if isNonError(n.Argument) {
+			a.report(metadata, n, ThrowNonErrorCode, "thrown value is not an Error, it has no stack trace")
+		}
+		if !Caught(metadata) {
+			summary := a.summary(function)
+			summary.Throws = append(summary.Throws, n)
+			summary.MayThrow = true
+		}
+	case *ast.CallExpression, *ast.NewExpression:
+		if !Caught(metadata) {
+			summary := a.summary(function)
+			summary.sites = append(summary.sites, n.(ast.Expression))
+		}
+	case *ast.CatchStatement:
+		if body, ok := n.Body.(*ast.BlockStatement); ok && len(body.List) == 0 && !commented(program, body) {
+			a.report(metadata, n, EmptyCatchCode, fmt.Sprintf("catch block ignores %v", n.Parameter.Name))
+		}
+	}
+}
+
+// propagate marks the functions calling functions that may throw, until nothing changes
+func (a *Analysis) propagate(graph *callgraph.Graph) {
+	callees := map[ast.Expression][]*Summary{}
+	for _, edge := range graph.Edges {
+		if edge.Callee.Function != nil {
+			callees[edge.Site] = append(callees[edge.Site], a.summary(edge.Callee.Function))
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, summary := range a.Summaries {
+			for _, site := range summary.sites {
+				if summary.calls(site) {
+					continue
+				}
+				for _, callee := range callees[site] {
+					if callee.MayThrow {
+						summary.Calls = append(summary.Calls, site)
+						summary.MayThrow = true
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// calls returns true if the site is already known to throw
+func (s *Summary) calls(site ast.Expression) bool {
+	for _, call := range s.Calls {
+		if call == site {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Caught returns true if exceptions thrown by the current node are caught by a try statement in the same function
+func Caught(metadata []walker.Metadata) bool {
+	for i := len(metadata) - 2; i >= 0; i-- {
+		switch n := metadata[i].Node().(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryStatement:
+			if n.Catch != nil && metadata[i+1].Node() == n.Body {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// commented returns true if the block contains a comment, e.g. explaining why an error is ignored.
+// The source is read if the comments were not stored by the parser.
+func commented(program *ast.Program, block *ast.BlockStatement) bool {
+	if len(program.Comments[block]) > 0 {
+		return true
+	}
+	if program.File == nil {
+		return false
+	}
+
+	base := program.File.Base()
+	start, end := int(block.LeftBrace)-base+1, int(block.RightBrace)-base
+	source := program.File.Source()
+	return start >= 0 && start <= end && end <= len(source) && strings.TrimSpace(source[start:end]) != ""
+}
+
+// isNonError returns true if the expression is a primitive or a literal object, which are not errors
+func isNonError(expression ast.Expression) bool {
+	switch e := expression.(type) {
+	case *ast.StringLiteral, *ast.NumberLiteral, *ast.BooleanLiteral, *ast.NullLiteral,
+		*ast.ObjectLiteral, *ast.ArrayLiteral, *ast.UnaryExpression:
+		return true
+	case *ast.BinaryExpression:
+		// Logical operators return one of their operands, the others return primitives
+		if e.Operator == token.LOGICAL_OR || e.Operator == token.LOGICAL_AND {
+			return isNonError(e.Left) && isNonError(e.Right)
+		}
+		return true
+	case *ast.ConditionalExpression:
+		return isNonError(e.Consequent) && isNonError(e.Alternate)
+	case *ast.Identifier:
+		return e.Name == "undefined"
+	}
+
+	return false
+}
+
+func (a *Analysis) report(metadata []walker.Metadata, node ast.Node, code, message string) {
+	diagnostic := walker.NewDiagnostic(node, walker.Warning, code, message)
+	diagnostic.Locate(walker.CurrentMetadata(metadata))
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
+
+// Hook returns a hook storing the summaries in the metadata of the functions and programs of a walk.
+// Hooks added after it read them with SummaryOf.
+func (a *Analysis) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+			switch node.(type) {
+			case *ast.Program, *ast.FunctionLiteral:
+				if summary, ok := a.Summaries[node]; ok {
+					walker.CurrentMetadata(metadata)[SummaryField] = summary
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// SummaryOf returns the summary of the function or program enclosing the current node, nil if none
+func SummaryOf(metadata []walker.Metadata) *Summary {
+	for i := len(metadata) - 1; i >= 0; i-- {
+		if summary, ok := metadata[i][SummaryField].(*Summary); ok {
+			return summary
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package exception
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+	"github.com/wolfgarnet/walker/callgraph"
+)
+
+func parse(t *testing.T, name, src string) *ast.Program {
+	program, err := parser.ParseFile(nil, name, src, 0)
+	if err != nil {
+		t.Fatalf("Failed, %v", err)
+	}
+
+	return program
+}
+
+// throwing returns the names of the functions of the programs that may throw, sorted.
+// Functions are named as in the call graph.
+func throwing(programs ...*ast.Program) string {
+	functions := map[ast.Node]string{}
+	for _, node := range callgraph.Build(programs...).Nodes {
+		functions[node.Function] = node.Name
+	}
+
+	var names []string
+	for node, summary := range Analyze(programs...).Summaries {
+		if !summary.MayThrow {
+			continue
+		}
+		switch n := node.(type) {
+		case *ast.Program:
+			names = append(names, "program")
+		case *ast.FunctionLiteral:
+			names = append(names, functions[n])
+		}
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, " ")
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`function a() { throw new Error("x") }`, "a"},
+		{`function a() { try { throw new Error("x") } catch (e) {} }`, ""},
+		{`function a() { try { throw new Error("x") } finally {} }`, "a"},
+		{`function a() { try { b() } catch (e) { throw e } }`, "a"},
+		{`function a() { throw new Error("x") } function b() { a() } function c() { try { b() } catch (e) { log(e) } }`, "a b"},
+		{`function a() { throw new Error("x") } var f = a; f()`, "a program"},
+		{`function a() { try { (function b() { throw new Error("x") })() } catch (e) { log(e) } }`, "b"},
+		{`function a() { a() }`, ""},
+		{`function a() { (function() { throw new Error("x") })() }`, "a function@1:17"},
+	}
+
+	for i, test := range tests {
+		if result := throwing(parse(t, "", test.src)); result != test.expected {
+			t.Errorf("[%v] Failed, %v: %q != %q", i, test.src, test.expected, result)
+		}
+	}
+}
+
+func TestMultiplePrograms(t *testing.T) {
+	a := parse(t, "a.js", `function fail() { throw new Error("x") }`)
+	b := parse(t, "b.js", `function run() { fail() }`)
+
+	if result := throwing(a, b); result != "fail run" {
+		t.Errorf("Failed, %q != %q", "fail run", result)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`throw "failed"`, ThrowNonErrorCode},
+		{`throw {code: 1}`, ThrowNonErrorCode},
+		{`throw "failed: " + reason`, ThrowNonErrorCode},
+		{`throw code * 2`, ThrowNonErrorCode},
+		{`throw err || new Error("x")`, ""},
+		{`throw err && "failed"`, ""},
+		{`throw code || "failed"`, ""},
+		{`throw "" || "failed"`, ThrowNonErrorCode},
+		{`throw failed ? "failed" : new Error("x")`, ""},
+		{`throw failed ? "failed" : 1`, ThrowNonErrorCode},
+		{`throw new Error("failed")`, ""},
+		{`throw error`, ""},
+		{`try { a() } catch (e) {}`, EmptyCatchCode},
+		{`try { a() } catch (e) { log(e) }`, ""},
+		{`try { a() } catch (e) { /* ignore */ }`, ""},
+		{"try { a() } catch (e) {\n  // ignore\n}", ""},
+	}
+
+	for i, test := range tests {
+		var codes []string
+		for _, d := range Analyze(parse(t, "", test.src)).Diagnostics {
+			codes = append(codes, d.Code)
+		}
+		if result := strings.Join(codes, " "); result != test.expected {
+			t.Errorf("[%v] Failed, %v: %q != %q", i, test.src, test.expected, result)
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	program := parse(t, "", `function a() { throw new Error("x") } function b() { return 1 }`)
+	analysis := Analyze(program)
+
+	found := map[string]bool{}
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(analysis.Hook())
+	visitor.AddHook(&walker.Hook{
+		OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+			if _, ok := node.(*ast.ReturnStatement); ok {
+				found["return"] = SummaryOf(metadata).MayThrow
+			}
+			if _, ok := node.(*ast.ThrowStatement); ok {
+				found["throw"] = SummaryOf(metadata).MayThrow
+			}
+			return nil
+		},
+	})
+	walker.NewWalker(visitor).Begin(program)
+
+	if !found["throw"] || found["return"] {
+		t.Errorf("Failed, summaries not correct, %v", found)
+	}
+}
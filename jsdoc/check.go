@@ -0,0 +1,298 @@
+package jsdoc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+)
+
+const (
+	// ArgumentCountCode is the code of diagnostics reporting calls with too few or too many arguments
+	ArgumentCountCode = "jsdoc-argument-count"
+	// ArgumentTypeCode is the code of diagnostics reporting literal arguments not matching the @param type
+	ArgumentTypeCode = "jsdoc-argument-type"
+	// DeprecatedCallCode is the code of diagnostics reporting calls of deprecated functions
+	DeprecatedCallCode = "jsdoc-deprecated"
+)
+
+// site is a call of a variable, or of a property of a variable
+type site struct {
+	call   ast.Expression
+	callee member
+}
+
+// Checker checks the calls of documented functions against their JSDoc.
+// Functions are resolved through the scopes, as variables or properties of objects assigned to variables.
+type Checker struct {
+	Extractor   *Extractor
+	Diagnostics []*walker.Diagnostic
+
+	sites []site
+}
+
+// NewChecker returns a new instance
+func NewChecker() *Checker {
+	return &Checker{
+		Extractor: NewExtractor(),
+	}
+}
+
+// Hook returns the hook collecting the documented symbols and the calls, they are checked when the walk is finished
+func (c *Checker) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode:     c.onNode,
+		OnFinished: c.onFinished,
+	}
+}
+
+func (c *Checker) onNode(node ast.Node, metadata []walker.Metadata) error {
+	c.Extractor.onNode(node, metadata)
+
+	switch n := node.(type) {
+	case *ast.CallExpression:
+		c.add(n, n.Callee, metadata)
+	case *ast.NewExpression:
+		c.add(n, n.Callee, metadata)
+	}
+
+	return nil
+}
+
+func (c *Checker) add(call, callee ast.Expression, metadata []walker.Metadata) {
+	switch e := callee.(type) {
+	case *ast.Identifier:
+		c.sites = append(c.sites, site{call, member{binding: walker.ResolveBinding(metadata, e.Name)}})
+	case *ast.DotExpression:
+		if object, ok := e.Left.(*ast.Identifier); ok {
+			c.sites = append(c.sites, site{call, member{binding: walker.ResolveBinding(metadata, object.Name), name: e.Identifier.Name}})
+		}
+	}
+}
+
+func (c *Checker) onFinished(node ast.Node, metadata walker.Metadata) error {
+	for _, s := range c.sites {
+		symbol, ok := c.Extractor.bound[s.callee]
+		if !ok || s.callee.binding.Unknown {
+			continue
+		}
+		c.check(metadata, s.call, symbol)
+	}
+
+	return nil
+}
+
+// check checks the call of the symbol
+func (c *Checker) check(md walker.Metadata, call ast.Expression, symbol *Symbol) {
+	if symbol.Doc.Deprecated {
+		message := fmt.Sprintf("%v is deprecated", symbol.Name)
+		if symbol.Doc.DeprecatedReason != "" {
+			message += ": " + symbol.Doc.DeprecatedReason
+		}
+		c.report(md, call, DeprecatedCallCode, message)
+	}
+
+	if symbol.Function == nil {
+		return
+	}
+
+	var arguments []ast.Expression
+	switch n := call.(type) {
+	case *ast.CallExpression:
+		arguments = n.ArgumentList
+	case *ast.NewExpression:
+		arguments = n.ArgumentList
+	}
+
+	params := parameters(symbol)
+	required, variadic := 0, usesArguments(symbol.Function.Body)
+	for i, param := range params {
+		if strings.HasPrefix(param.Type, "...") {
+			variadic = true
+		} else if !param.Optional {
+			required = i + 1
+		}
+	}
+
+	if len(arguments) < required || len(arguments) > len(params) && !variadic {
+		c.report(md, call, ArgumentCountCode, fmt.Sprintf("%v expects %v arguments, got %v", symbol.Name, count(required, len(params), variadic), len(arguments)))
+	}
+
+	for i, argument := range arguments {
+		if i >= len(params) || params[i].Type == "" {
+			continue
+		}
+		kind := literalType(argument)
+		if kind != "" && !accepts(params[i].Type, kind) {
+			message := fmt.Sprintf("argument %v of %v is %v, expected %v", params[i].Name, symbol.Name, article(kind), params[i].Type)
+			c.report(md, argument, ArgumentTypeCode, message)
+		}
+	}
+}
+
+func (c *Checker) report(md walker.Metadata, node ast.Node, code, message string) {
+	diagnostic := walker.NewDiagnostic(node, walker.Warning, code, message)
+	diagnostic.Locate(md)
+	c.Diagnostics = append(c.Diagnostics, diagnostic)
+}
+
+// parameters returns the parameters of the function, with their @param tags when documented
+func parameters(symbol *Symbol) []*Param {
+	var params []*Param
+	for _, parameter := range symbol.Function.ParameterList.List {
+		param := symbol.Doc.Param(parameter.Name)
+		if param == nil {
+			param = &Param{Name: parameter.Name, Optional: len(symbol.Doc.Params) == 0}
+		}
+		params = append(params, param)
+	}
+
+	return params
+}
+
+// count describes the number of expected arguments
+func count(required, total int, variadic bool) string {
+	switch {
+	case variadic:
+		return fmt.Sprintf("at least %v", required)
+	case required == total:
+		return fmt.Sprint(total)
+	}
+
+	return fmt.Sprintf("%v to %v", required, total)
+}
+
+// usesArguments returns true if the function body reads the arguments object
+func usesArguments(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		return false
+	case *ast.Identifier:
+		return n.Name == "arguments"
+	}
+
+	for _, child := range walker.Children(node) {
+		if usesArguments(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literalType returns the type of literal expressions, the empty string for other expressions
+func literalType(expression ast.Expression) string {
+	switch e := expression.(type) {
+	case *ast.NumberLiteral:
+		return "number"
+	case *ast.StringLiteral:
+		return "string"
+	case *ast.BooleanLiteral:
+		return "boolean"
+	case *ast.NullLiteral:
+		return "null"
+	case *ast.ObjectLiteral:
+		return "object"
+	case *ast.ArrayLiteral:
+		return "array"
+	case *ast.FunctionLiteral:
+		return "function"
+	case *ast.RegExpLiteral:
+		return "regexp"
+	case *ast.Identifier:
+		if e.Name == "undefined" {
+			return "undefined"
+		}
+	case *ast.UnaryExpression:
+		if e.Operator == token.TYPEOF {
+			return "string"
+		}
+	}
+
+	return ""
+}
+
+// accepts returns true if the JSDoc type expression accepts values of the literal type.
+// Names of classes and other unknown types accept any object and null.
+func accepts(expression, kind string) bool {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") {
+		expression = expression[1 : len(expression)-1]
+	}
+	if strings.HasPrefix(expression, "?") && len(expression) > 1 {
+		if kind == "null" {
+			return true
+		}
+		expression = expression[1:]
+	}
+	expression = strings.TrimPrefix(strings.TrimSuffix(expression, "="), "!")
+	expression = strings.TrimPrefix(expression, "...")
+
+	for _, alternative := range split(expression) {
+		if acceptsType(strings.TrimSpace(alternative), kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func acceptsType(name, kind string) bool {
+	primitive := kind != "object" && kind != "array" && kind != "function" && kind != "regexp"
+
+	switch lower := strings.ToLower(name); {
+	case name == "" || name == "*" || name == "?" || lower == "any" || lower == "mixed":
+		return true
+	case lower == "number" || lower == "string" || lower == "boolean" || lower == "null":
+		return lower == kind
+	case lower == "undefined" || lower == "void":
+		return kind == "undefined"
+	case lower == "object":
+		return !primitive || kind == "null"
+	case lower == "array" || strings.HasSuffix(name, "[]") || strings.HasPrefix(name, "Array.<") || strings.HasPrefix(name, "Array<"):
+		return kind == "array" || kind == "null"
+	case lower == "function" || strings.HasPrefix(name, "function("):
+		return kind == "function" || kind == "null"
+	case lower == "regexp":
+		return kind == "regexp" || kind == "null"
+	case strings.HasPrefix(name, "{"):
+		return kind == "object" || kind == "null"
+	}
+
+	return !primitive || kind == "null"
+}
+
+// split splits the union at the top level
+func split(expression string) []string {
+	var alternatives []string
+	depth, start := 0, 0
+	for i, r := range expression {
+		switch r {
+		case '(', '{', '<', '[':
+			depth++
+		case ')', '}', '>', ']':
+			depth--
+		case '|':
+			if depth == 0 {
+				alternatives = append(alternatives, expression[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(alternatives, expression[start:])
+}
+
+// article returns the type with its indefinite article
+func article(kind string) string {
+	switch kind {
+	case "null", "undefined":
+		return kind
+	case "object", "array":
+		return "an " + kind
+	}
+
+	return "a " + kind
+}
@@ -0,0 +1,208 @@
+package jsdoc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/wolfgarnet/walker"
+)
+
+// ParamMismatchCode is the code of diagnostics reporting @param tags not matching the parameters of the function
+const ParamMismatchCode = "jsdoc-param-mismatch"
+
+// Kind is the kind of a documented symbol
+type Kind string
+
+const (
+	Function Kind = "function"
+	Variable Kind = "variable"
+	Property Kind = "property"
+)
+
+// Symbol is a documented function, variable or object property
+type Symbol struct {
+	// Name is the name of the symbol, properties of objects assigned to variables are prefixed with the variable, e.g. "o.name"
+	Name string
+	Kind Kind
+	// Node is the *ast.FunctionStatement, *ast.VariableExpression or the value of the property
+	Node ast.Node
+	// Function is the documented function, nil if the symbol is not a function
+	Function *ast.FunctionLiteral
+	// Line is the line of the symbol, 0 if the file is not known
+	Line int
+	Doc  *Doc
+}
+
+// Extractor collects the documented symbols of the walked programs.
+// Functions are documented by a leading JSDoc comment, variables by a comment leading the var statement,
+// which documents its first variable, and properties by a comment preceding their key.
+type Extractor struct {
+	Symbols []*Symbol
+	// Functions maps the documented functions to their symbols
+	Functions   map[*ast.FunctionLiteral]*Symbol
+	Diagnostics []*walker.Diagnostic
+
+	// bound maps the variables and the properties of objects assigned to variables to their symbols
+	bound map[member]*Symbol
+}
+
+// member is a variable, or a property of the object assigned to it if name is set
+type member struct {
+	binding walker.Binding
+	name    string
+}
+
+// NewExtractor returns a new instance
+func NewExtractor() *Extractor {
+	return &Extractor{
+		Functions: map[*ast.FunctionLiteral]*Symbol{},
+		bound:     map[member]*Symbol{},
+	}
+}
+
+// Hook returns the hook collecting the symbols
+func (e *Extractor) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode: e.onNode,
+	}
+}
+
+func (e *Extractor) onNode(node ast.Node, metadata []walker.Metadata) error {
+	current := walker.CurrentMetadata(metadata)
+	comments := current.Comments()
+
+	switch n := node.(type) {
+	case *ast.FunctionStatement:
+		if doc := lastDoc(comments.Leading); doc != nil {
+			symbol := &Symbol{Name: n.Function.Name.Name, Kind: Function, Node: n, Function: n.Function, Doc: doc}
+			e.add(current, symbol)
+			e.bind(member{binding: walker.ResolveBinding(metadata, symbol.Name)}, symbol)
+		}
+	case *ast.VariableStatement:
+		if doc := lastDoc(comments.Leading); doc != nil && len(n.List) > 0 {
+			if variable, ok := n.List[0].(*ast.VariableExpression); ok {
+				symbol := &Symbol{Name: variable.Name, Kind: Variable, Node: variable, Function: function(variable.Initializer), Doc: doc}
+				e.add(current, symbol)
+				e.bind(member{binding: walker.ResolveBinding(metadata, symbol.Name)}, symbol)
+			}
+		}
+	}
+
+	// Comments preceding keys are attached to the leftmost node of the value
+	var keys []*ast.Comment
+	for _, comment := range comments.Inner {
+		if comment.Position == ast.KEY {
+			keys = append(keys, comment)
+		}
+	}
+	if doc := lastDoc(keys); doc != nil {
+		if name, m, md := property(metadata); md != nil {
+			value := md.Node().(ast.Expression)
+			symbol := &Symbol{Name: name, Kind: Property, Node: value, Function: function(value), Doc: doc}
+			e.add(md, symbol)
+			if m.name != "" {
+				e.bind(m, symbol)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Extractor) add(md walker.Metadata, symbol *Symbol) {
+	if r := md.Range(); r != nil {
+		symbol.Line = r.Start.Line
+	}
+	e.Symbols = append(e.Symbols, symbol)
+
+	if symbol.Function != nil {
+		e.Functions[symbol.Function] = symbol
+		e.check(md, symbol)
+	}
+}
+
+// bind associates the symbol with the variable or property, unless it is resolved in a dynamic scope
+func (e *Extractor) bind(m member, symbol *Symbol) {
+	if !m.binding.Unknown {
+		e.bound[m] = symbol
+	}
+}
+
+// check reports the @param tags not matching a parameter, and the parameters not documented by a @param tag
+func (e *Extractor) check(md walker.Metadata, symbol *Symbol) {
+	if len(symbol.Doc.Params) == 0 {
+		return
+	}
+
+	parameters := map[string]bool{}
+	for _, parameter := range symbol.Function.ParameterList.List {
+		parameters[parameter.Name] = true
+	}
+
+	documented := map[string]bool{}
+	for _, param := range symbol.Doc.Params {
+		// Properties of parameters are documented as "options.name"
+		name := strings.SplitN(param.Name, ".", 2)[0]
+		documented[name] = true
+		if !parameters[name] {
+			e.report(md, symbol.Node, fmt.Sprintf("@param %v does not match a parameter of %v", param.Name, symbol.Name))
+		}
+	}
+
+	for _, parameter := range symbol.Function.ParameterList.List {
+		if !documented[parameter.Name] {
+			e.report(md, parameter, fmt.Sprintf("parameter %v of %v is not documented", parameter.Name, symbol.Name))
+		}
+	}
+}
+
+func (e *Extractor) report(md walker.Metadata, node ast.Node, message string) {
+	diagnostic := walker.NewDiagnostic(node, walker.Warning, ParamMismatchCode, message)
+	diagnostic.Locate(md)
+	e.Diagnostics = append(e.Diagnostics, diagnostic)
+}
+
+// property returns the name, the member and the metadata of the value of the property of the closest object literal
+// containing the current node. The member is only set for objects assigned to variables.
+func property(metadata []walker.Metadata) (string, member, walker.Metadata) {
+	for i := len(metadata) - 2; i >= 0; i-- {
+		object, ok := metadata[i].Node().(*ast.ObjectLiteral)
+		if !ok {
+			continue
+		}
+
+		for _, p := range object.Value {
+			if p.Value != metadata[i+1].Node() {
+				continue
+			}
+			name, m := p.Key, member{}
+			if variable, ok := walker.ParentMetadata(metadata[:i+1]).Node().(*ast.VariableExpression); ok {
+				name = variable.Name + "." + name
+				m = member{binding: walker.ResolveBinding(metadata[:i], variable.Name), name: p.Key}
+			}
+			return name, m, metadata[i+1]
+		}
+		return "", member{}, nil
+	}
+
+	return "", member{}, nil
+}
+
+// lastDoc parses the last JSDoc comment of the list, nil if none
+func lastDoc(comments []*ast.Comment) *Doc {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if IsDoc(comments[i].Text) {
+			return Parse(comments[i].Text)
+		}
+	}
+
+	return nil
+}
+
+// function returns the expression if it is a function literal, nil otherwise
+func function(expression ast.Expression) *ast.FunctionLiteral {
+	literal, _ := expression.(*ast.FunctionLiteral)
+
+	return literal
+}
@@ -0,0 +1,128 @@
+package jsdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Markdown returns the documentation of the symbols as Markdown, one section per symbol
+func Markdown(symbols []*Symbol) string {
+	var buffer bytes.Buffer
+	for _, symbol := range symbols {
+		fmt.Fprintf(&buffer, "## %v\n\n", signature(symbol))
+		doc := symbol.Doc
+		if doc.Deprecated {
+			buffer.WriteString("**Deprecated**")
+			if doc.DeprecatedReason != "" {
+				buffer.WriteString(": " + doc.DeprecatedReason)
+			}
+			buffer.WriteString("\n\n")
+		}
+		if doc.Description != "" {
+			buffer.WriteString(doc.Description + "\n\n")
+		}
+		if doc.Type != "" {
+			fmt.Fprintf(&buffer, "Type: `%v`\n\n", doc.Type)
+		}
+
+		if len(doc.Params) > 0 {
+			buffer.WriteString("| Parameter | Type | Description |\n|---|---|---|\n")
+			for _, param := range doc.Params {
+				name := param.Name
+				if param.Optional {
+					name += " (optional)"
+				}
+				description := param.Description
+				if param.Default != "" {
+					description = join(description, "(default `"+param.Default+"`)")
+				}
+				fmt.Fprintf(&buffer, "| %v | %v | %v |\n", escape(name), code(param.Type), escape(description))
+			}
+			buffer.WriteString("\n")
+		}
+
+		if doc.Returns != nil {
+			buffer.WriteString("Returns")
+			if doc.Returns.Type != "" {
+				buffer.WriteString(" " + code(doc.Returns.Type))
+			}
+			if doc.Returns.Description != "" {
+				buffer.WriteString(": " + doc.Returns.Description)
+			}
+			buffer.WriteString("\n\n")
+		}
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// signature returns the name of the symbol, with the parameters of functions
+func signature(symbol *Symbol) string {
+	if symbol.Function == nil {
+		return symbol.Name
+	}
+
+	var parameters []string
+	for _, parameter := range symbol.Function.ParameterList.List {
+		parameters = append(parameters, parameter.Name)
+	}
+
+	return symbol.Name + "(" + strings.Join(parameters, ", ") + ")"
+}
+
+func code(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return "`" + escape(text) + "`"
+}
+
+// escape escapes the text for table cells
+func escape(text string) string {
+	return strings.Replace(text, "|", "\\|", -1)
+}
+
+type jsonSymbol struct {
+	Name             string   `json:"name"`
+	Kind             Kind     `json:"kind"`
+	Line             int      `json:"line,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	Params           []*Param `json:"params,omitempty"`
+	Returns          *Returns `json:"returns,omitempty"`
+	Type             string   `json:"type,omitempty"`
+	Deprecated       bool     `json:"deprecated,omitempty"`
+	DeprecatedReason string   `json:"deprecatedReason,omitempty"`
+	Tags             []*Tag   `json:"tags,omitempty"`
+	Parameters       []string `json:"parameters,omitempty"`
+}
+
+// JSON returns the documentation of the symbols as a JSON array
+func JSON(symbols []*Symbol) ([]byte, error) {
+	result := []jsonSymbol{}
+	for _, symbol := range symbols {
+		doc := symbol.Doc
+		s := jsonSymbol{
+			Name:             symbol.Name,
+			Kind:             symbol.Kind,
+			Line:             symbol.Line,
+			Description:      doc.Description,
+			Params:           doc.Params,
+			Returns:          doc.Returns,
+			Type:             doc.Type,
+			Deprecated:       doc.Deprecated,
+			DeprecatedReason: doc.DeprecatedReason,
+			Tags:             doc.Tags,
+		}
+		if symbol.Function != nil {
+			for _, parameter := range symbol.Function.ParameterList.List {
+				s.Parameters = append(s.Parameters, parameter.Name)
+			}
+		}
+		result = append(result, s)
+	}
+
+	return json.MarshalIndent(result, "", "  ")
+}
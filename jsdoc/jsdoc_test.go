@@ -0,0 +1,232 @@
+package jsdoc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+)
+
+func TestParse(t *testing.T) {
+	doc := Parse(`*
+ * Formats a value.
+ *
+ * Uses the locale.
+ * @param {string|number} value - The value
+ *   to format
+ * @param {{digits: number}} [options={digits: 2}] The options
+ * @param {boolean=} strict
+ * @returns {string} The text
+ * @deprecated Use format2
+ * @since 1.2
+ `)
+
+	if doc.Description != "Formats a value.\n\nUses the locale." {
+		t.Errorf("Failed, description not correct, %q", doc.Description)
+	}
+
+	expected := []Param{
+		{Name: "value", Type: "string|number", Description: "The value to format"},
+		{Name: "options", Type: "{digits: number}", Description: "The options", Optional: true, Default: "{digits: 2}"},
+		{Name: "strict", Type: "boolean", Optional: true},
+	}
+	if len(doc.Params) != len(expected) {
+		t.Fatalf("Failed, number of params not correct, %v", len(doc.Params))
+	}
+	for i, param := range doc.Params {
+		if *param != expected[i] {
+			t.Errorf("[%v] Failed, param not correct, %+v != %+v", i, expected[i], *param)
+		}
+	}
+
+	if doc.Returns == nil || doc.Returns.Type != "string" || doc.Returns.Description != "The text" {
+		t.Errorf("Failed, returns not correct, %+v", doc.Returns)
+	}
+	if !doc.Deprecated || doc.DeprecatedReason != "Use format2" {
+		t.Errorf("Failed, deprecation not correct, %v %q", doc.Deprecated, doc.DeprecatedReason)
+	}
+	if len(doc.Tags) != 1 || doc.Tags[0].Name != "since" || doc.Tags[0].Text != "1.2" {
+		t.Errorf("Failed, tags not correct, %v", doc.Tags)
+	}
+	if Parse("* @type {Array.<string>}").Type != "Array.<string>" {
+		t.Errorf("Failed, type not correct")
+	}
+}
+
+func extract(t *testing.T, src string) *Extractor {
+	program, err := parser.ParseFile(nil, "", src, parser.StoreComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extractor := NewExtractor()
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(extractor.Hook())
+	walker.NewWalker(visitor).Begin(program)
+
+	return extractor
+}
+
+func TestExtractor(t *testing.T) {
+	src := `/**
+ * Adds numbers.
+ * @param {number} a
+ * @param {number} c
+ */
+function add(a, b) { return a + b }
+
+// Not documented
+function other() {}
+
+/** @type {number} */
+var limit = 10, unused;
+
+/** Helpers */
+var helpers = {
+	/**
+	 * Trims the text.
+	 * @param {string} text
+	 */
+	trim: function(text) { return text.trim() },
+	/** @deprecated */
+	name: "helpers"
+};`
+
+	extractor := extract(t, src)
+
+	var names []string
+	for _, symbol := range extractor.Symbols {
+		names = append(names, string(symbol.Kind)+" "+symbol.Name)
+	}
+	expected := "function add, variable limit, variable helpers, property helpers.trim, property helpers.name"
+	if strings.Join(names, ", ") != expected {
+		t.Errorf("Failed, symbols not correct, %v", strings.Join(names, ", "))
+	}
+
+	var messages []string
+	for _, d := range extractor.Diagnostics {
+		messages = append(messages, d.Message)
+	}
+	expected = "@param c does not match a parameter of add, parameter b of add is not documented"
+	if strings.Join(messages, ", ") != expected {
+		t.Errorf("Failed, diagnostics not correct, %v", strings.Join(messages, ", "))
+	}
+
+	trim := extractor.Symbols[3]
+	if trim.Function == nil || extractor.Functions[trim.Function] != trim || trim.Line != 20 {
+		t.Errorf("Failed, property function not correct, %+v", trim)
+	}
+	if !extractor.Symbols[4].Doc.Deprecated {
+		t.Errorf("Failed, property doc not correct")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	extractor := extract(t, `/**
+ * Adds numbers.
+ * @param {number} a The first
+ * @param {number} [b=1] The second
+ * @returns {number} The sum
+ * @deprecated Use plus
+ */
+function add(a, b) { return a + b }
+/** @type {string|null} */
+var name = null;`)
+
+	expected := "## add(a, b)\n\n**Deprecated**: Use plus\n\nAdds numbers.\n\n" +
+		"| Parameter | Type | Description |\n|---|---|---|\n" +
+		"| a | `number` | The first |\n| b (optional) | `number` | The second (default `1`) |\n\n" +
+		"Returns `number`: The sum\n\n" +
+		"## name\n\nType: `string|null`\n"
+	if result := Markdown(extractor.Symbols); result != expected {
+		t.Errorf("Failed, markdown not correct\n%v", result)
+	}
+
+	data, err := JSON(extractor.Symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || result[0]["name"] != "add" || result[0]["line"] != 8.0 || result[1]["type"] != "string|null" {
+		t.Errorf("Failed, JSON not correct, %s", data)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	src := `/**
+ * @param {number} a
+ * @param {string|null} [b]
+ */
+function add(a, b) {}
+
+/** @deprecated Use add */
+function plus(a, b) {}
+
+/** @param {...number} values */
+function sum(values) { return arguments.length }
+
+/** @param {Point} p */
+var move = function(p) {};
+
+var shapes = {
+	/** @param {Array.<number>} points */
+	polygon: function(points) {}
+};
+
+add(1);
+add(1, "b");
+add(1, null, 3);
+add();
+add("1", 2);
+plus(1, 2);
+sum(1, 2, 3);
+sum("1");
+move({x: 1});
+move(1);
+shapes.polygon([1, 2]);
+shapes.polygon({});
+new add(true, undefined);
+
+function shadow(add) {
+	add("x", 1, 2);
+}
+with (other) {
+	add("x");
+}`
+
+	program, err := parser.ParseFile(nil, "", src, parser.StoreComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := NewChecker()
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(checker.Hook())
+	walker.NewWalker(visitor).Begin(program)
+
+	var messages []string
+	for _, d := range checker.Diagnostics {
+		messages = append(messages, d.Code+": "+d.Message)
+	}
+
+	expected := []string{
+		"jsdoc-argument-count: add expects 1 to 2 arguments, got 3",
+		"jsdoc-argument-count: add expects 1 to 2 arguments, got 0",
+		"jsdoc-argument-type: argument a of add is a string, expected number",
+		"jsdoc-argument-type: argument b of add is a number, expected string|null",
+		"jsdoc-deprecated: plus is deprecated: Use add",
+		"jsdoc-argument-type: argument values of sum is a string, expected ...number",
+		"jsdoc-argument-type: argument p of move is a number, expected Point",
+		"jsdoc-argument-type: argument points of shapes.polygon is an object, expected Array.<number>",
+		"jsdoc-argument-type: argument a of add is a boolean, expected number",
+		"jsdoc-argument-type: argument b of add is undefined, expected string|null",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Failed, diagnostics not correct\n%v", strings.Join(messages, "\n"))
+	}
+}
@@ -0,0 +1,176 @@
+// Package jsdoc extracts JSDoc comments of functions, variables and properties, and generates documentation from them.
+package jsdoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Param is a @param tag
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Optional is true for names in brackets, e.g. [name] or [name=default]
+	Optional bool   `json:"optional,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// Returns is a @returns tag
+type Returns struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag is a tag without a dedicated field
+type Tag struct {
+	Name string `json:"name"`
+	Text string `json:"text,omitempty"`
+}
+
+// Doc is a parsed JSDoc comment
+type Doc struct {
+	Description string
+	Params      []*Param
+	Returns     *Returns
+	// Type is the type of a @type tag
+	Type       string
+	Deprecated bool
+	// DeprecatedReason is the text of the @deprecated tag
+	DeprecatedReason string
+	Tags             []*Tag
+}
+
+// IsDoc returns true if the text of the comment is a JSDoc block, i.e. the comment starts with /**
+func IsDoc(text string) bool {
+	return strings.HasPrefix(text, "*") && !strings.HasPrefix(text, "**")
+}
+
+// Param returns the @param tag of the name, nil if not documented
+func (d *Doc) Param(name string) *Param {
+	for _, param := range d.Params {
+		if param.Name == name {
+			return param
+		}
+	}
+
+	return nil
+}
+
+// Parse parses the text of a JSDoc comment, without the /* and */ delimiters
+func Parse(text string) *Doc {
+	doc := &Doc{}
+
+	var description []string
+	// appendText continues the description of the last tag
+	appendText := func(line string) {
+		description = append(description, line)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+		if !strings.HasPrefix(line, "@") {
+			appendText(line)
+			continue
+		}
+
+		name, rest := cut(line[1:])
+		switch name {
+		case "param", "arg", "argument":
+			param := parseParam(rest)
+			doc.Params = append(doc.Params, param)
+			appendText = func(line string) { param.Description = join(param.Description, line) }
+		case "returns", "return":
+			returns := &Returns{}
+			returns.Type, returns.Description = parseType(rest)
+			doc.Returns = returns
+			appendText = func(line string) { returns.Description = join(returns.Description, line) }
+		case "type":
+			doc.Type, _ = parseType(rest)
+			appendText = func(string) {}
+		case "deprecated":
+			doc.Deprecated = true
+			doc.DeprecatedReason = rest
+			appendText = func(line string) { doc.DeprecatedReason = join(doc.DeprecatedReason, line) }
+		default:
+			tag := &Tag{Name: name, Text: rest}
+			doc.Tags = append(doc.Tags, tag)
+			appendText = func(line string) { tag.Text = join(tag.Text, line) }
+		}
+	}
+
+	doc.Description = strings.TrimSpace(strings.Join(description, "\n"))
+
+	return doc
+}
+
+// parseParam parses "{type} name description"
+func parseParam(text string) *Param {
+	param := &Param{}
+	param.Type, text = parseType(text)
+	param.Name, param.Description = cut(text)
+
+	if strings.HasPrefix(param.Name, "[") {
+		// The name may contain spaces in the default value
+		if end := strings.Index(text, "]"); end > 0 {
+			param.Name, param.Description = text[1:end], strings.TrimSpace(text[end+1:])
+		} else {
+			param.Name = strings.TrimPrefix(param.Name, "[")
+		}
+		param.Optional = true
+		if i := strings.Index(param.Name, "="); i >= 0 {
+			param.Name, param.Default = strings.TrimSpace(param.Name[:i]), strings.TrimSpace(param.Name[i+1:])
+		}
+	}
+	if strings.HasSuffix(param.Type, "=") {
+		param.Type = strings.TrimSuffix(param.Type, "=")
+		param.Optional = true
+	}
+	param.Description = strings.TrimSpace(strings.TrimPrefix(param.Description, "- "))
+
+	return param
+}
+
+// parseType returns the type in braces at the start of the text, and the rest of the text
+func parseType(text string) (string, string) {
+	if !strings.HasPrefix(text, "{") {
+		return "", text
+	}
+
+	depth := 0
+	for i, r := range text {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(text[1:i]), strings.TrimSpace(text[i+1:])
+			}
+		}
+	}
+
+	return strings.TrimSpace(text[1:]), ""
+}
+
+// cut splits the text at the first space
+func cut(text string) (string, string) {
+	i := strings.IndexFunc(text, unicode.IsSpace)
+	if i < 0 {
+		return text, ""
+	}
+
+	return text[:i], strings.TrimSpace(text[i:])
+}
+
+func join(text, line string) string {
+	if text == "" {
+		return line
+	}
+	if line == "" {
+		return text
+	}
+
+	return text + " " + line
+}
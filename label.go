@@ -0,0 +1,135 @@
+package walker
+
+import (
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+const (
+	UndefinedLabelCode  = "undefined-label"
+	UnusedLabelCode     = "unused-label"
+	IllegalBranchCode   = "illegal-branch"
+	ContinueNonLoopCode = "continue-non-loop"
+)
+
+// IsLoop returns true if the node is an iteration statement
+func IsLoop(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.ForStatement, *ast.ForInStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+		return true
+	}
+
+	return false
+}
+
+// Target returns the statement targeted by the branch statement of the metadata, nil if none
+func (md Metadata) Target() ast.Statement {
+	target, ok := md[Target].(ast.Statement)
+	if !ok {
+		return nil
+	}
+
+	return target
+}
+
+// FindBranchTarget returns the statement targeted by the break or continue, nil if none.
+// The target is the innermost loop or switch, or the statement of the given label.
+// The labelled statement is returned as well, if the branch is labelled.
+func FindBranchTarget(metadata []Metadata, branch *ast.BranchStatement) (ast.Statement, *ast.LabelledStatement) {
+	for i := len(metadata) - 1; i >= 0; i-- {
+		node := metadata[i].Node()
+
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			// Labels are not visible across functions
+			return nil, nil
+		case *ast.LabelledStatement:
+			if branch.Label != nil && branch.Label.Name == n.Label.Name {
+				return labelledTarget(n), n
+			}
+		case *ast.SwitchStatement:
+			if branch.Label == nil && branch.Token == token.BREAK {
+				return n, nil
+			}
+		}
+
+		if branch.Label == nil && IsLoop(node) {
+			return node.(ast.Statement), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// labelledTarget returns the statement of the labelled statement, skipping nested labels
+func labelledTarget(labelled *ast.LabelledStatement) ast.Statement {
+	statement := labelled.Statement
+	for {
+		nested, ok := statement.(*ast.LabelledStatement)
+		if !ok {
+			return statement
+		}
+		statement = nested.Statement
+	}
+}
+
+// LabelAnalyzer reports undefined and unused labels, and break and continue statements without a valid target
+type LabelAnalyzer struct {
+	Diagnostics []*Diagnostic
+	used        map[*ast.LabelledStatement]bool
+}
+
+// NewLabelAnalyzer returns a new instance
+func NewLabelAnalyzer() *LabelAnalyzer {
+	return &LabelAnalyzer{
+		used: map[*ast.LabelledStatement]bool{},
+	}
+}
+
+// Hook returns the hook performing the analysis
+func (a *LabelAnalyzer) Hook() *Hook {
+	return &Hook{
+		OnNode:      a.onNode,
+		OnNodeLeave: a.onNodeLeave,
+	}
+}
+
+func (a *LabelAnalyzer) onNode(node ast.Node, metadata []Metadata) error {
+	branch, ok := node.(*ast.BranchStatement)
+	if !ok {
+		return nil
+	}
+
+	target, labelled := FindBranchTarget(metadata, branch)
+	switch {
+	case branch.Label != nil && labelled == nil:
+		a.report(metadata, branch, Error, UndefinedLabelCode, fmt.Sprintf("undefined label %v", branch.Label.Name))
+	case target == nil:
+		a.report(metadata, branch, Error, IllegalBranchCode, fmt.Sprintf("%v outside of loop or switch", branch.Token))
+	case branch.Token == token.CONTINUE && !IsLoop(target):
+		a.report(metadata, branch, Error, ContinueNonLoopCode, fmt.Sprintf("continue targets %v, which is not a loop", branch.Label.Name))
+	}
+
+	if labelled != nil {
+		a.used[labelled] = true
+	}
+
+	return nil
+}
+
+func (a *LabelAnalyzer) onNodeLeave(node ast.Node, metadata []Metadata) error {
+	labelled, ok := node.(*ast.LabelledStatement)
+	if ok && !a.used[labelled] {
+		a.report(metadata, labelled, Warning, UnusedLabelCode, fmt.Sprintf("unused label %v", labelled.Label.Name))
+	}
+
+	return nil
+}
+
+func (a *LabelAnalyzer) report(metadata []Metadata, node ast.Node, severity Severity, code, message string) {
+	diagnostic := NewDiagnostic(node, severity, code, message)
+	diagnostic.Locate(CurrentMetadata(metadata))
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
@@ -0,0 +1,96 @@
+package walker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/robertkrimen/otto/token"
+)
+
+type branchVisitor struct {
+	VisitorImpl
+	targets []ast.Statement
+}
+
+func (v *branchVisitor) VisitBranch(w *Walker, node *ast.BranchStatement, metadata []Metadata) Metadata {
+	v.targets = append(v.targets, CurrentMetadata(metadata).Target())
+
+	return v.VisitorImpl.VisitBranch(w, node, metadata)
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		src         string
+		target      reflect.Type
+		diagnostics []string
+	}{
+		{`while (a) { break }`, reflect.TypeOf((*ast.WhileStatement)(nil)), nil},
+		{`for (;;) { switch (a) { case 1: break } }`, reflect.TypeOf((*ast.SwitchStatement)(nil)), nil},
+		{`for (;;) { switch (a) { case 1: continue } }`, reflect.TypeOf((*ast.ForStatement)(nil)), nil},
+		{`a: for (;;) { while (b) { continue a } }`, reflect.TypeOf((*ast.ForStatement)(nil)), nil},
+		{`a: b: do { break a } while (c)`, reflect.TypeOf((*ast.DoWhileStatement)(nil)), []string{UnusedLabelCode}},
+		{`a: { break a }`, reflect.TypeOf((*ast.BlockStatement)(nil)), nil},
+		{`a: while (b) { c() }`, nil, []string{UnusedLabelCode}},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		analyzer := NewLabelAnalyzer()
+		visitor := &branchVisitor{}
+		visitor.AddHook(analyzer.Hook())
+		NewWalker(visitor).Begin(program)
+
+		if test.target != nil {
+			if len(visitor.targets) != 1 || reflect.TypeOf(visitor.targets[0]) != test.target {
+				t.Errorf("[%v] Failed, target not correct, %v != %v", i, test.target, visitor.targets)
+			}
+		}
+
+		var codes []string
+		for _, diagnostic := range analyzer.Diagnostics {
+			codes = append(codes, diagnostic.Code)
+		}
+		if !reflect.DeepEqual(test.diagnostics, codes) {
+			t.Errorf("[%v] Failed, diagnostics not correct, %v != %v", i, test.diagnostics, codes)
+		}
+	}
+}
+
+func TestInvalidBranches(t *testing.T) {
+	// The parser refuses these, so the statements are built by hand
+	label := func(name string) *ast.Identifier {
+		return &ast.Identifier{Name: name}
+	}
+	tests := []struct {
+		node ast.Node
+		code string
+	}{
+		{&ast.LabelledStatement{Label: label("a"), Statement: &ast.BlockStatement{List: []ast.Statement{
+			&ast.BranchStatement{Token: token.CONTINUE, Label: label("a")},
+		}}}, ContinueNonLoopCode},
+		{&ast.WhileStatement{Test: label("b"), Body: &ast.BranchStatement{Token: token.BREAK, Label: label("a")}}, UndefinedLabelCode},
+		{&ast.BlockStatement{List: []ast.Statement{&ast.BranchStatement{Token: token.CONTINUE}}}, IllegalBranchCode},
+		{&ast.WhileStatement{Test: label("b"), Body: &ast.ExpressionStatement{Expression: &ast.FunctionLiteral{
+			ParameterList: &ast.ParameterList{},
+			Body:          &ast.BranchStatement{Token: token.BREAK},
+		}}}, IllegalBranchCode},
+	}
+
+	for i, test := range tests {
+		analyzer := NewLabelAnalyzer()
+		visitor := &VisitorImpl{}
+		visitor.AddHook(analyzer.Hook())
+		NewWalker(visitor).Begin(test.node)
+
+		if len(analyzer.Diagnostics) != 1 || analyzer.Diagnostics[0].Code != test.code {
+			t.Errorf("[%v] Failed, diagnostics not correct, %v != %v", i, test.code, analyzer.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package walker
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// PathAt returns the metadata of the nodes enclosing the range of byte offsets, from the root to the innermost node.
+// The offsets are relative to the source of the file, an empty range selects the node at the start offset.
+// Nil is returned if no node encloses the range.
+func PathAt(root ast.Node, f *file.File, start, end int) []Metadata {
+	if end < start {
+		start, end = end, start
+	}
+
+	spans := map[ast.Node][2]int{}
+	var path []Metadata
+	visitor := &VisitorImpl{}
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			// Deeper nodes are preferred, the first one if siblings touch
+			if len(metadata) > len(path) && encloses(span(f, node, spans), start, end) {
+				path = append([]Metadata(nil), metadata...)
+			}
+			return nil
+		},
+	})
+	walker := NewWalker(visitor)
+	walker.File = f
+	walker.Begin(root)
+
+	return path
+}
+
+// NodeAt returns the innermost node at the byte offset in the source of the file, nil if none
+func NodeAt(root ast.Node, f *file.File, offset int) ast.Node {
+	return CurrentMetadata(PathAt(root, f, offset, offset)).Node()
+}
+
+// NodeAtPosition returns the innermost node at the 1-based line and column, nil if none
+func NodeAtPosition(root ast.Node, f *file.File, line, column int) ast.Node {
+	offset := Offset(f, line, column)
+	if offset < 0 {
+		return nil
+	}
+
+	return NodeAt(root, f, offset)
+}
+
+// Offset returns the byte offset of the 1-based line and column in the source of the file, -1 if it is outside
+func Offset(f *file.File, line, column int) int {
+	if line < 1 || column < 1 {
+		return -1
+	}
+
+	source := f.Source()
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := strings.IndexByte(source[offset:], '\n')
+		if next < 0 {
+			return -1
+		}
+		offset += next + 1
+	}
+
+	length := strings.IndexByte(source[offset:], '\n')
+	if length < 0 {
+		length = len(source) - offset
+	}
+	if column > length+1 {
+		return -1
+	}
+
+	return offset + column - 1
+}
+
+// span returns the offsets of the node, widened to include its children as some nodes report partial ranges.
+// The offsets are -1 if neither the node nor its children have an index, e.g. for an empty program.
+func span(f *file.File, node ast.Node, spans map[ast.Node][2]int) [2]int {
+	if s, ok := spans[node]; ok {
+		return s
+	}
+
+	s := [2]int{-1, -1}
+	idx0, idx1 := indexes(node)
+	if idx0 > 0 {
+		s[0] = int(idx0) - f.Base()
+	}
+	if idx1 > 0 {
+		s[1] = int(idx1) - f.Base()
+	}
+	for _, child := range Children(node) {
+		c := span(f, child, spans)
+		if c[0] >= 0 && (s[0] < 0 || c[0] < s[0]) {
+			s[0] = c[0]
+		}
+		if c[1] > s[1] {
+			s[1] = c[1]
+		}
+	}
+	switch n := node.(type) {
+	case *ast.CaseStatement:
+		if len(n.Consequent) == 0 && s[1] >= 0 {
+			// The clause ends with the colon
+			s[1] = past(f.Source(), s[1], ':', ")")
+		}
+	case *ast.SwitchStatement:
+		if s[1] >= 0 {
+			s[1] = past(f.Source(), s[1], '}', "){;")
+		}
+	case *ast.DoWhileStatement:
+		// Otto ends the statement with its test, the parentheses enclosing the test may be nested
+		for s[1] >= 0 {
+			end := past(f.Source(), s[1], ')', "")
+			if end == s[1] {
+				break
+			}
+			s[1] = end
+		}
+	}
+	if word := keyword(node); word != "" && s[0] >= 0 {
+		s[0] = keywordBefore(f.Source(), word, s[0])
+	}
+	if s[0] < 0 {
+		s[0] = s[1]
+	}
+	if s[1] < s[0] {
+		s[1] = s[0]
+	}
+	spans[node] = s
+
+	return s
+}
+
+// indexes returns the indexes of the node which do not depend on its children, 0 if they are not known.
+// Otto computes the indexes of some nodes from their first or last child, which panics if there is none,
+// e.g. for an empty program or case clause, and does not keep the keyword of some statements.
+func indexes(node ast.Node) (file.Idx, file.Idx) {
+	switch n := node.(type) {
+	case *ast.Program, *ast.SequenceExpression:
+		return 0, 0
+	case *ast.CaseStatement:
+		if n.Test == nil {
+			return n.Case, n.Case + file.Idx(len("default"))
+		}
+		return n.Case, 0
+	case *ast.SwitchStatement:
+		return n.Switch, 0
+	case *ast.IfStatement:
+		return n.If, 0
+	case *ast.ForStatement:
+		return n.For, 0
+	case *ast.ForInStatement:
+		return n.For, 0
+	case *ast.WhileStatement:
+		return n.While, 0
+	case *ast.WithStatement:
+		return n.With, 0
+	}
+
+	return node.Idx0(), node.Idx1()
+}
+
+// keyword returns the keyword starting the statement if otto does not keep its index
+func keyword(node ast.Node) string {
+	switch node.(type) {
+	case *ast.DoWhileStatement:
+		return "do"
+	case *ast.ForStatement, *ast.ForInStatement:
+		return "for"
+	case *ast.IfStatement:
+		return "if"
+	case *ast.SwitchStatement:
+		return "switch"
+	case *ast.WhileStatement:
+		return "while"
+	case *ast.WithStatement:
+		return "with"
+	}
+
+	return ""
+}
+
+// keywordBefore returns the offset of the keyword preceding the offset, only separated from it by whitespace, comments,
+// parentheses and the other tokens of the header of the statement. The offset is returned if the keyword is not found.
+func keywordBefore(source, word string, offset int) int {
+	if offset > len(source) || strings.HasPrefix(source[offset:], word) {
+		return offset
+	}
+
+	skipped := "("
+	if word == "for" {
+		// The first child of a for statement may follow empty clauses or var
+		skipped = "(;)"
+	}
+	before := skipBack(source, offset, skipped)
+	if word == "for" && endsWithWord(source[:before], "var") {
+		before = skipBack(source, before-len("var"), skipped)
+	}
+	if endsWithWord(source[:before], word) {
+		return before - len(word)
+	}
+
+	return offset
+}
+
+// endsWithWord returns true if the text ends with the word, not preceded by a character of an identifier
+func endsWithWord(text, word string) bool {
+	k := len(text) - len(word)
+	return strings.HasSuffix(text, word) && (k == 0 || !identifierPart(text[k-1]))
+}
+
+// past returns the offset following the character if it is the next token, after whitespace, comments and the characters of skipped.
+// The offset is returned if another token precedes it.
+func past(source string, offset int, c byte, skipped string) int {
+	for next := offset; next >= 0; next++ {
+		next = skip(source, next)
+		if next >= len(source) {
+			break
+		}
+		if source[next] == c {
+			return next + 1
+		}
+		if strings.IndexByte(skipped, source[next]) < 0 {
+			break
+		}
+	}
+
+	return offset
+}
+
+// skip returns the offset of the next token, skipping whitespace and comments
+func skip(source string, offset int) int {
+	for offset < len(source) {
+		rest := source[offset:]
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			next := strings.IndexByte(rest, '\n')
+			if next < 0 {
+				return len(source)
+			}
+			offset += next
+		case strings.HasPrefix(rest, "/*"):
+			next := strings.Index(rest[2:], "*/")
+			if next < 0 {
+				return len(source)
+			}
+			offset += next + 4
+		case strings.IndexByte(" \t\r\n\v\f", rest[0]) >= 0:
+			offset++
+		default:
+			return offset
+		}
+	}
+
+	return offset
+}
+
+// skipBack returns the offset following the previous token, skipping whitespace, comments and the characters of skipped
+func skipBack(source string, offset int, skipped string) int {
+	newline := false
+	for offset > 0 {
+		before := source[:offset]
+		c := before[len(before)-1]
+		switch {
+		case strings.HasSuffix(before, "*/"):
+			start := strings.LastIndex(before[:len(before)-2], "/*")
+			if start < 0 {
+				return offset
+			}
+			offset = start
+		case c == '\n':
+			newline = true
+			offset--
+		case strings.IndexByte(" \t\r\v\f"+skipped, c) >= 0:
+			offset--
+		default:
+			// A line comment may end a previous line
+			line := before[strings.LastIndexByte(before, '\n')+1:]
+			if k := strings.Index(line, "//"); newline && k >= 0 {
+				offset -= len(line) - k
+				newline = false
+				continue
+			}
+			return offset
+		}
+	}
+
+	return offset
+}
+
+// identifierPart returns true if the character can be part of an identifier
+func identifierPart(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// nodeRange returns the indexes of the node in the file, see span. The start of the file is returned if they are not known.
+func nodeRange(f *file.File, node ast.Node) (file.Idx, file.Idx) {
+	s := span(f, node, map[ast.Node][2]int{})
+	if s[0] < 0 {
+		return file.Idx(f.Base()), file.Idx(f.Base())
+	}
+
+	return file.Idx(s[0] + f.Base()), file.Idx(s[1] + f.Base())
+}
+
+// encloses returns true if the span contains the offsets
+func encloses(s [2]int, start, end int) bool {
+	idx0, idx1 := s[0], s[1]
+	if start == end {
+		return idx0 <= start && start < idx1
+	}
+
+	return idx0 <= start && end <= idx1
+}
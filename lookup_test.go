@@ -0,0 +1,149 @@
+package walker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestNodeAt(t *testing.T) {
+	src := "var total = 0;\nfunction add(value) {\n  total += value * 2\n}"
+	program, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line, column int
+		expected     string
+	}{
+		{1, 5, "*ast.VariableExpression"},
+		{1, 13, "*ast.NumberLiteral"},
+		{2, 10, "*ast.Identifier add"},
+		{2, 14, "*ast.Identifier value"},
+		{3, 3, "*ast.Identifier total"},
+		{3, 9, "*ast.AssignExpression"},
+		{3, 18, "*ast.BinaryExpression"},
+		{3, 20, "*ast.NumberLiteral"},
+		{2, 20, "*ast.FunctionLiteral"},
+		{3, 30, "<nil>"},
+		{9, 1, "<nil>"},
+	}
+
+	for i, test := range tests {
+		node := NodeAtPosition(program, program.File, test.line, test.column)
+		result := fmt.Sprintf("%T", node)
+		if identifier, ok := node.(*ast.Identifier); ok {
+			result += " " + identifier.Name
+		}
+		if result != test.expected {
+			t.Errorf("[%v] Failed, node at %v:%v not correct, %v != %v", i, test.line, test.column, test.expected, result)
+		}
+	}
+}
+
+func TestPathAt(t *testing.T) {
+	src := "if (a) { call(a, b + c) }"
+	program, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		{17, 22, "*ast.BinaryExpression"},
+		{14, 18, "*ast.CallExpression"},
+		{4, 5, "*ast.Identifier"},
+		{4, 9, "*ast.IfStatement"},
+		{4, 25, "*ast.IfStatement"},
+	}
+
+	for i, test := range tests {
+		path := PathAt(program, program.File, test.start, test.end)
+		if len(path) < 2 || path[1].Node() != program {
+			t.Errorf("[%v] Failed, path does not start with the program, %v", i, path)
+			continue
+		}
+		if result := fmt.Sprintf("%T", CurrentMetadata(path).Node()); result != test.expected {
+			t.Errorf("[%v] Failed, node enclosing %v-%v not correct, %v != %v", i, test.start, test.end, test.expected, result)
+		}
+	}
+
+	// The path resolves the variables of the enclosing scopes
+	program, err = parser.ParseFile(nil, "", "var x; function f() { return x }", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := PathAt(program, program.File, 30, 30)
+	if FindVariable(path, "x") != 5 {
+		t.Errorf("Failed, variable not resolved from the path")
+	}
+}
+
+func TestNodeAtEmptyNodes(t *testing.T) {
+	tests := []struct {
+		src      string
+		offset   int
+		expected string
+	}{
+		{"", 0, "<nil>"},
+		{"// comment only\n", 3, "<nil>"},
+		{"switch (a) { case 1: case 2: b() }", 18, "*ast.NumberLiteral"},
+		{"switch (a) { case 1: case 2: b() }", 29, "*ast.Identifier"},
+		{"switch (a) { case 1: }", 18, "*ast.NumberLiteral"},
+		{"for (;;) { switch (a) { default: } }", 25, "*ast.CaseStatement"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+		if result := fmt.Sprintf("%T", NodeAt(program, program.File, test.offset)); result != test.expected {
+			t.Errorf("[%v] Failed, node at %v not correct, %v != %v", i, test.offset, test.expected, result)
+		}
+	}
+}
+
+func TestNodeRangeStatements(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"switch (a) {}", "switch (a) {}"},
+		{"switch ((a)) {} x()", "switch ((a)) {}"},
+		{"switch (a) { case 1: x() /* c */ }", "switch (a) { case 1: x() /* c */ }"},
+		{"switch (a) { case 1: x(); }", "switch (a) { case 1: x(); }"},
+		{"do { x() } while (a)", "do { x() } while (a)"},
+		{"do x(); while ((a)); y()", "do x(); while ((a))"},
+		{"do /* do */ { x() } while (a)", "do /* do */ { x() } while (a)"},
+		{"while (/* while */ a) {}", "while (/* while */ a) {}"},
+		{"x = 'if'; if // if\n(a) {}", "if // if\n(a) {}"},
+		{"for (;;) {}", "for (;;) {}"},
+		{"for (var i = 0; i < 1;) {}", "for (var i = 0; i < 1;) {}"},
+		{"for (var k in o) {}", "for (var k in o) {}"},
+		{"with (o) {}", "with (o) {}"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		statement := program.Body[0]
+		if _, ok := statement.(*ast.ExpressionStatement); ok {
+			statement = program.Body[1]
+		}
+		idx0, idx1 := nodeRange(program.File, statement)
+		if result := test.src[int(idx0)-1 : int(idx1)-1]; result != test.expected {
+			t.Errorf("[%v] Failed, range not correct, %q != %q", i, test.expected, result)
+		}
+	}
+}
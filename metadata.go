@@ -7,8 +7,15 @@ import (
 )
 
 const (
-	Vars      string = "vars"
-	NodeField        = "node"
+	Vars           string = "vars"
+	NodeField             = "node"
+	Dynamic               = "dynamic"
+	Target                = "target"
+	Strict                = "strict"
+	FileField             = "file"
+	RangeField            = "range"
+	CommentsField         = "comments"
+	SourceMapField        = "sourcemap"
 )
 
 // Metadata contains information about a node.
@@ -36,6 +43,51 @@ func (md Metadata) AddParent(parent ast.Node) {
 	md[NodeField] = parent
 }
 
+// Range is the source range of a node, the end is the position following it
+type Range struct {
+	Start, End file.Position
+}
+
+// Range returns the range of the node, including its children, computed on first use.
+// Nil is returned if the file is not known.
+func (md Metadata) Range() *Range {
+	if r, ok := md[RangeField].(*Range); ok {
+		return r
+	}
+
+	f, _ := md[FileField].(*file.File)
+	node := md.Node()
+	if f == nil || node == nil {
+		return nil
+	}
+
+	idx0, idx1 := nodeRange(f, node)
+	r := &Range{
+		Start: offsetPosition(f, idx0),
+		End:   offsetPosition(f, idx1),
+	}
+	md[RangeField] = r
+
+	return r
+}
+
+// Position returns the position of the index in the original source if it is mapped by the source map of the metadata,
+// else in the file of the metadata. Nil is returned if the file is not known.
+func (md Metadata) Position(idx file.Idx) *file.Position {
+	f, _ := md[FileField].(*file.File)
+	m, _ := md[SourceMapField].(*SourceMap)
+
+	return position(f, m, idx)
+}
+
+// offsetPosition returns the position of the index, clamped to the source of the file
+func offsetPosition(f *file.File, idx file.Idx) file.Position {
+	offset := clamp(int(idx)-f.Base(), 0, len(f.Source()))
+	line, column := lineColumn(f.Source(), offset)
+
+	return file.Position{Filename: f.Name(), Offset: offset, Line: line, Column: column}
+}
+
 // CurrentMetadata returns the last added element as the current metadata
 func CurrentMetadata(metadata []Metadata) Metadata {
 	l := len(metadata)
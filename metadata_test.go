@@ -0,0 +1,108 @@
+package walker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestRange(t *testing.T) {
+	src := "var f = function(a) {\n  return a + 1\n}"
+	program, err := parser.ParseFile(nil, "test.js", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	literal := program.Body[0].(*ast.VariableStatement).List[0].(*ast.VariableExpression).Initializer
+
+	ranges := map[string]*Range{}
+	visitor := &VisitorImpl{}
+	walker := NewWalker(visitor)
+	walker.File = program.File
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			switch n := node.(type) {
+			case *ast.FunctionLiteral:
+				ranges["function"] = CurrentMetadata(metadata).Range()
+			case *ast.BinaryExpression:
+				ranges["binary"] = CurrentMetadata(metadata).Range()
+			case *ast.Identifier:
+				if n.Name == "a" {
+					ranges["a"] = CurrentMetadata(metadata).Range()
+				}
+			}
+			return nil
+		},
+	})
+	walker.Begin(literal)
+
+	tests := []struct {
+		name        string
+		line0, col0 int
+		line1, col1 int
+	}{
+		{"function", 1, 9, 3, 2},
+		{"binary", 2, 10, 2, 15},
+		{"a", 2, 10, 2, 11},
+	}
+
+	for i, test := range tests {
+		r := ranges[test.name]
+		if r == nil {
+			t.Errorf("[%v] Failed, no range for %v", i, test.name)
+			continue
+		}
+		if r.Start.Line != test.line0 || r.Start.Column != test.col0 || r.End.Line != test.line1 || r.End.Column != test.col1 {
+			t.Errorf("[%v] Failed, range of %v not correct, %v:%v-%v:%v", i, test.name, r.Start.Line, r.Start.Column, r.End.Line, r.End.Column)
+		}
+	}
+
+	if position := walker.GetPosition(literal.Idx0()); position == nil || position.Line != 1 || position.Column != 9 {
+		t.Errorf("Failed, position not correct, %v", position)
+	}
+
+	if r := NewMetadata(literal).Range(); r != nil {
+		t.Errorf("Failed, expected no range without a file, %v", r)
+	}
+}
+
+func TestRangeEmptyNodes(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"", []string{"*ast.Program 1:1-1:1"}},
+		{"// comment only", []string{"*ast.Program 1:1-1:1"}},
+		{"switch (a) { case 1: case 2: b() }", []string{
+			"*ast.Program 1:1-1:35", "*ast.SwitchStatement 1:1-1:35", "*ast.Identifier 1:9-1:10",
+			"*ast.CaseStatement 1:14-1:21", "*ast.NumberLiteral 1:19-1:20", "*ast.CaseStatement 1:22-1:33",
+			"*ast.NumberLiteral 1:27-1:28", "*ast.ExpressionStatement 1:30-1:33", "*ast.CallExpression 1:30-1:33",
+			"*ast.Identifier 1:30-1:31",
+		}},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "test.js", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		var ranges []string
+		visitor := &VisitorImpl{}
+		visitor.AddHook(&Hook{
+			OnNode: func(node ast.Node, metadata []Metadata) error {
+				r := CurrentMetadata(metadata).Range()
+				ranges = append(ranges, fmt.Sprintf("%T %v:%v-%v:%v", node, r.Start.Line, r.Start.Column, r.End.Line, r.End.Column))
+				return nil
+			},
+		})
+		NewWalker(visitor).Begin(program)
+
+		if strings.Join(ranges, ", ") != strings.Join(test.expected, ", ") {
+			t.Errorf("[%v] Failed, ranges not correct, %q", i, ranges)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// Package metrics computes complexity metrics of the functions of programs walked by the walker.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+	"github.com/wolfgarnet/walker/callgraph"
+)
+
+// Halstead holds the operator and operand counts of the Halstead measures
+type Halstead struct {
+	DistinctOperators int
+	DistinctOperands  int
+	Operators         int
+	Operands          int
+}
+
+// Vocabulary returns the number of distinct operators and operands
+func (h Halstead) Vocabulary() int {
+	return h.DistinctOperators + h.DistinctOperands
+}
+
+// Length returns the total number of operators and operands
+func (h Halstead) Length() int {
+	return h.Operators + h.Operands
+}
+
+// Volume returns the length times the logarithm of the vocabulary
+func (h Halstead) Volume() float64 {
+	if h.Vocabulary() == 0 {
+		return 0
+	}
+
+	return float64(h.Length()) * math.Log2(float64(h.Vocabulary()))
+}
+
+// Difficulty returns how hard the code is to write or understand
+func (h Halstead) Difficulty() float64 {
+	if h.DistinctOperands == 0 {
+		return 0
+	}
+
+	return float64(h.DistinctOperators) / 2 * float64(h.Operands) / float64(h.DistinctOperands)
+}
+
+// Effort returns the difficulty times the volume
+func (h Halstead) Effort() float64 {
+	return h.Difficulty() * h.Volume()
+}
+
+// Function holds the metrics of a function, or the top level code of a program.
+// Nested functions are not part of the metrics of the enclosing function.
+type Function struct {
+	Name string
+	// Node is the *ast.FunctionLiteral, or the *ast.Program for the top level code
+	Node ast.Node
+	Line int
+
+	// Complexity is the cyclomatic complexity, one plus the number of decision points
+	Complexity int
+	// Depth is the maximum nesting depth of control statements
+	Depth      int
+	Statements int
+	Parameters int
+	Halstead   Halstead
+
+	operators map[string]bool
+	operands  map[string]bool
+}
+
+// Column of the report
+type Column int
+
+const (
+	Name Column = iota
+	Line
+	Complexity
+	Depth
+	Statements
+	Parameters
+	Volume
+	Difficulty
+	Effort
+)
+
+var columns = []string{"name", "line", "complexity", "depth", "statements", "parameters", "volume", "difficulty", "effort"}
+
+// String returns the name of the column
+func (c Column) String() string {
+	if int(c) < len(columns) {
+		return columns[c]
+	}
+
+	return "unknown"
+}
+
+// ParseColumn returns the column of the given name
+func ParseColumn(name string) (Column, error) {
+	for i, column := range columns {
+		if column == strings.ToLower(name) {
+			return Column(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown column %v", name)
+}
+
+// value returns the value of the column for the function, the name is not a number
+func (f *Function) value(column Column) float64 {
+	switch column {
+	case Line:
+		return float64(f.Line)
+	case Complexity:
+		return float64(f.Complexity)
+	case Depth:
+		return float64(f.Depth)
+	case Statements:
+		return float64(f.Statements)
+	case Parameters:
+		return float64(f.Parameters)
+	case Volume:
+		return f.Halstead.Volume()
+	case Difficulty:
+		return f.Halstead.Difficulty()
+	case Effort:
+		return f.Halstead.Effort()
+	}
+
+	return 0
+}
+
+// Limits are the maximum allowed values of the metrics, zero means no limit
+type Limits struct {
+	Complexity int
+	Depth      int
+	Statements int
+	Parameters int
+}
+
+// Report holds the metrics of the functions, in order of appearance
+type Report struct {
+	Functions []*Function
+}
+
+// Compute returns the report of the program and its functions, the top level code first
+func Compute(program *ast.Program) *Report {
+	report := &Report{}
+	collector := &collector{
+		report:    report,
+		program:   program,
+		functions: map[ast.Node]*Function{},
+	}
+
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(&walker.Hook{
+		OnNode: collector.onNode,
+	})
+	walker.NewWalker(visitor).Begin(program)
+
+	for _, function := range report.Functions {
+		function.Halstead.DistinctOperators = len(function.operators)
+		function.Halstead.DistinctOperands = len(function.operands)
+	}
+
+	return report
+}
+
+// Sort sorts the functions by the column, functions with equal values keep their order
+func (r *Report) Sort(column Column, descending bool) {
+	sort.SliceStable(r.Functions, func(i, j int) bool {
+		a, b := r.Functions[i], r.Functions[j]
+		if descending {
+			a, b = b, a
+		}
+		if column == Name {
+			return a.Name < b.Name
+		}
+		return a.value(column) < b.value(column)
+	})
+}
+
+// Exceeding returns the functions exceeding any of the limits
+func (r *Report) Exceeding(limits Limits) []*Function {
+	exceeds := func(value, limit int) bool {
+		return limit > 0 && value > limit
+	}
+
+	var result []*Function
+	for _, f := range r.Functions {
+		if exceeds(f.Complexity, limits.Complexity) || exceeds(f.Depth, limits.Depth) ||
+			exceeds(f.Statements, limits.Statements) || exceeds(f.Parameters, limits.Parameters) {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+// String returns the report as a table
+func (r *Report) String() string {
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(columns, "\t"))
+	for _, f := range r.Functions {
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%.1f\t%.1f\t%.1f\n", f.Name, f.Line, f.Complexity, f.Depth, f.Statements, f.Parameters,
+			f.Halstead.Volume(), f.Halstead.Difficulty(), f.Halstead.Effort())
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
+
+// collector computes the metrics during the walk
+type collector struct {
+	report    *Report
+	program   *ast.Program
+	functions map[ast.Node]*Function
+}
+
+// function returns the metrics of the node, creating them if needed
+func (c *collector) function(node ast.Node, metadata []walker.Metadata) *Function {
+	if f, ok := c.functions[node]; ok {
+		return f
+	}
+
+	f := &Function{
+		Name:       "program",
+		Node:       node,
+		Complexity: 1,
+		operators:  map[string]bool{},
+		operands:   map[string]bool{},
+	}
+	// Otto reads the first statement for the index of a program and does not keep the index of some statements
+	if program, ok := node.(*ast.Program); c.program.File != nil && (!ok || len(program.Body) > 0) {
+		if position := c.program.File.Position(node.Idx0()); position != nil {
+			f.Line = position.Line
+		}
+	}
+	if literal, ok := node.(*ast.FunctionLiteral); ok {
+		f.Name = functionName(c.program, literal, metadata)
+		f.Parameters = len(literal.ParameterList.List)
+	}
+
+	c.functions[node] = f
+	c.report.Functions = append(c.report.Functions, f)
+
+	return f
+}
+
+// functionName returns the name of the function, or of the variable or property it is assigned to.
+// Other functions are named as in the call graph.
+func functionName(program *ast.Program, literal *ast.FunctionLiteral, metadata []walker.Metadata) string {
+	if literal.Name != nil {
+		return literal.Name.Name
+	}
+
+	switch p := walker.ParentMetadata(metadata).Node().(type) {
+	case *ast.VariableExpression:
+		return p.Name
+	case *ast.AssignExpression:
+		switch left := p.Left.(type) {
+		case *ast.Identifier:
+			return left.Name
+		case *ast.DotExpression:
+			return left.Identifier.Name
+		}
+	case *ast.ObjectLiteral:
+		for _, property := range p.Value {
+			if property.Value == literal {
+				return property.Key
+			}
+		}
+	}
+
+	return callgraph.FunctionName(program, literal)
+}
+
+func (c *collector) onNode(node ast.Node, metadata []walker.Metadata) error {
+	var f *Function
+	if literal := walker.FindParentFunction(metadata); literal != nil {
+		f = c.function(literal, metadata)
+	} else {
+		f = c.function(c.program, metadata)
+	}
+
+	if _, isFunction := node.(*ast.FunctionLiteral); isFunction {
+		// The function keyword is an operator of the enclosing function
+		if parent := walker.FindParentFunction(metadata[:len(metadata)-1]); parent != nil {
+			c.function(parent, metadata[:len(metadata)-1]).operator("function")
+		} else {
+			c.function(c.program, nil).operator("function")
+		}
+		return nil
+	}
+
+	if _, ok := node.(ast.Statement); ok {
+		if _, isBlock := node.(*ast.BlockStatement); !isBlock {
+			f.Statements++
+		}
+	}
+	if isDecision(node) {
+		f.Complexity++
+	}
+	if isNesting(node, metadata) {
+		if depth := nesting(metadata); depth > f.Depth {
+			f.Depth = depth
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.Identifier:
+		f.operand(n.Name)
+	case *ast.NumberLiteral:
+		f.operand(n.Literal)
+	case *ast.StringLiteral:
+		f.operand(n.Literal)
+	case *ast.BooleanLiteral:
+		f.operand(n.Literal)
+	case *ast.NullLiteral:
+		f.operand("null")
+	case *ast.RegExpLiteral:
+		f.operand(n.Literal)
+	case *ast.ThisExpression:
+		f.operand("this")
+	case *ast.VariableExpression:
+		f.operand(n.Name)
+		if n.Initializer != nil {
+			f.operator("=")
+		}
+	case *ast.BinaryExpression:
+		f.operator(n.Operator.String())
+	case *ast.AssignExpression:
+		if n.Operator == token.ASSIGN {
+			f.operator("=")
+		} else {
+			f.operator(n.Operator.String() + "=")
+		}
+	case *ast.UnaryExpression:
+		operator := n.Operator.String()
+		if n.Postfix {
+			operator = "post" + operator
+		}
+		f.operator(operator)
+	case *ast.CallExpression:
+		f.operator("()")
+	case *ast.NewExpression:
+		f.operator("new")
+	case *ast.DotExpression:
+		f.operator(".")
+	case *ast.BracketExpression:
+		f.operator("[]")
+	case *ast.ConditionalExpression:
+		f.operator("?:")
+	case *ast.SequenceExpression:
+		f.operator(",")
+	case *ast.ArrayLiteral:
+		f.operator("[...]")
+	case *ast.ObjectLiteral:
+		f.operator("{...}")
+		for _, property := range n.Value {
+			f.operand(property.Key)
+		}
+	case *ast.VariableStatement:
+		f.operator("var")
+	case *ast.IfStatement:
+		f.operator("if")
+	case *ast.ForStatement:
+		f.operator("for")
+	case *ast.ForInStatement:
+		f.operator("for in")
+	case *ast.WhileStatement:
+		f.operator("while")
+	case *ast.DoWhileStatement:
+		f.operator("do while")
+	case *ast.SwitchStatement:
+		f.operator("switch")
+	case *ast.CaseStatement:
+		if n.Test != nil {
+			f.operator("case")
+		} else {
+			f.operator("default")
+		}
+	case *ast.BranchStatement:
+		f.operator(n.Token.String())
+	case *ast.ReturnStatement:
+		f.operator("return")
+	case *ast.ThrowStatement:
+		f.operator("throw")
+	case *ast.TryStatement:
+		f.operator("try")
+		if n.Finally != nil {
+			f.operator("finally")
+		}
+	case *ast.CatchStatement:
+		f.operator("catch")
+	case *ast.WithStatement:
+		f.operator("with")
+	case *ast.LabelledStatement:
+		f.operator(":")
+	}
+
+	return nil
+}
+
+func (f *Function) operator(name string) {
+	f.operators[name] = true
+	f.Halstead.Operators++
+}
+
+func (f *Function) operand(name string) {
+	f.operands[name] = true
+	f.Halstead.Operands++
+}
+
+// isDecision returns true if the node adds a path through the function
+func isDecision(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.IfStatement, *ast.ConditionalExpression, *ast.ForStatement, *ast.ForInStatement,
+		*ast.WhileStatement, *ast.DoWhileStatement, *ast.CatchStatement:
+		return true
+	case *ast.CaseStatement:
+		return n.Test != nil
+	case *ast.BinaryExpression:
+		return n.Operator == token.LOGICAL_AND || n.Operator == token.LOGICAL_OR
+	}
+
+	return false
+}
+
+// isNesting returns true if the node is a control statement nesting its body.
+// The if statement of an else if does not nest, it continues the chain.
+func isNesting(node ast.Node, metadata []walker.Metadata) bool {
+	switch node.(type) {
+	case *ast.IfStatement:
+		parent, ok := walker.ParentMetadata(metadata).Node().(*ast.IfStatement)
+		return !ok || parent.Alternate != node
+	case *ast.ForStatement, *ast.ForInStatement, *ast.WhileStatement, *ast.DoWhileStatement,
+		*ast.SwitchStatement, *ast.TryStatement, *ast.WithStatement:
+		return true
+	}
+
+	return false
+}
+
+// nesting returns the number of nesting statements enclosing the current node, including it, up to the function
+func nesting(metadata []walker.Metadata) int {
+	depth := 0
+	for i := len(metadata) - 1; i >= 0; i-- {
+		node := metadata[i].Node()
+		if _, isFunction := node.(*ast.FunctionLiteral); isFunction {
+			break
+		}
+		if node != nil && isNesting(node, metadata[:i+1]) {
+			depth++
+		}
+	}
+
+	return depth
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		src        string
+		name       string
+		complexity int
+		depth      int
+		statements int
+		parameters int
+	}{
+		{`function f() {}`, "f", 1, 0, 0, 0},
+		{`function f(a, b) { if (a && b) { return 1 } return 2 }`, "f", 3, 1, 3, 2},
+		{`var f = function(a) { for (;;) { while (a) { if (a) {} } } }`, "f", 4, 3, 3, 1},
+		{`o.f = function(a) { if (a) {} else if (b) {} else if (c) {} }`, "f", 4, 1, 3, 1},
+		{`x = { f: function() { switch (a) { case 1: break; default: } } }`, "f", 2, 1, 4, 0},
+		{`function f() { try { g() } catch (e) { h() } return a ? 1 : 2 }`, "f", 3, 1, 5, 0},
+		{`function f() { function g() { if (a) {} } }`, "f", 1, 0, 1, 0},
+		{"\n  (function(a) { if (a) {} })()", "function@2:4", 2, 1, 1, 1},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		report := Compute(program)
+		if len(report.Functions) < 2 {
+			t.Errorf("[%v] Failed, function not found", i)
+			continue
+		}
+
+		f := report.Functions[1]
+		if f.Name != test.name {
+			t.Errorf("[%v] Failed, name not correct, %v != %v", i, test.name, f.Name)
+		}
+		if f.Complexity != test.complexity {
+			t.Errorf("[%v] Failed, complexity not correct, %v != %v", i, test.complexity, f.Complexity)
+		}
+		if f.Depth != test.depth {
+			t.Errorf("[%v] Failed, depth not correct, %v != %v", i, test.depth, f.Depth)
+		}
+		if f.Statements != test.statements {
+			t.Errorf("[%v] Failed, statements not correct, %v != %v", i, test.statements, f.Statements)
+		}
+		if f.Parameters != test.parameters {
+			t.Errorf("[%v] Failed, parameters not correct, %v != %v", i, test.parameters, f.Parameters)
+		}
+	}
+}
+
+func TestComputeScripts(t *testing.T) {
+	tests := []struct {
+		src        string
+		complexity int
+		line       int
+	}{
+		{``, 1, 0},
+		{`// A comment only`, 1, 0},
+		{`switch (a) { case 1: case 2: b() }`, 3, 0},
+		{"\nvar a = 1", 1, 2},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		report := Compute(program)
+		if len(report.Functions) != 1 || report.Functions[0].Name != "program" {
+			t.Errorf("[%v] Failed, program not found", i)
+			continue
+		}
+		if f := report.Functions[0]; f.Complexity != test.complexity || f.Line != test.line {
+			t.Errorf("[%v] Failed, complexity or line not correct, %v %v", i, f.Complexity, f.Line)
+		}
+	}
+}
+
+func TestHalstead(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", `function f(a) { return a + a * 2 }`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Operators: return + *, operands: f a a a 2
+	h := Compute(program).Functions[1].Halstead
+	if h.DistinctOperators != 3 || h.Operators != 3 || h.DistinctOperands != 3 || h.Operands != 5 {
+		t.Errorf("Failed, counts not correct, %+v", h)
+	}
+	if h.Vocabulary() != 6 || h.Length() != 8 {
+		t.Errorf("Failed, vocabulary or length not correct, %v, %v", h.Vocabulary(), h.Length())
+	}
+	if difficulty := h.Difficulty(); difficulty != 2.5 {
+		t.Errorf("Failed, difficulty not correct, 2.5 != %v", difficulty)
+	}
+}
+
+func TestReport(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", `function a() {} function b(x) { if (x) {} } function c(x, y) { if (x || y) {} }`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Compute(program)
+	column, err := ParseColumn("Complexity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Sort(column, true)
+
+	var names []string
+	for _, f := range report.Functions {
+		names = append(names, f.Name)
+	}
+	if result := strings.Join(names, " "); result != "c b program a" {
+		t.Errorf("Failed, order not correct, %v", result)
+	}
+
+	exceeding := report.Exceeding(Limits{Complexity: 2})
+	if len(exceeding) != 1 || exceeding[0].Name != "c" {
+		t.Errorf("Failed, exceeding functions not correct, %v", exceeding)
+	}
+
+	if table := report.String(); !strings.HasPrefix(table, "name ") || !strings.Contains(table, "\nc ") {
+		t.Errorf("Failed, table not correct, %v", table)
+	}
+
+	if _, err := ParseColumn("size"); err == nil {
+		t.Error("Failed, expected error for unknown column")
+	}
+}
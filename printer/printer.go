@@ -0,0 +1,787 @@
+// Package printer prints otto ASTs as JavaScript source.
+package printer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker/constant"
+)
+
+// Precedences of expressions, from the loosest to the tightest binding
+const (
+	sequence = iota
+	assignment
+	conditional
+	logicalOr
+	logicalAnd
+	bitwiseOr
+	bitwiseXor
+	bitwiseAnd
+	equality
+	relational
+	shift
+	additive
+	multiplicative
+	unary
+	postfix
+	call
+	member
+	primary
+)
+
+// Config configures the printing
+type Config struct {
+	// Indent is the indentation of one level, a tab if empty
+	Indent string
+	// Compact prints the source on one line, without optional spaces
+	Compact bool
+}
+
+// Fprint prints the node with the default configuration
+func Fprint(output io.Writer, node ast.Node) error {
+	return (&Config{}).Fprint(output, node)
+}
+
+// Fprint prints the node, which is either an expression, a statement or a program.
+// Programs and statements end with a line break unless the output is compact.
+func (c *Config) Fprint(output io.Writer, node ast.Node) error {
+	text, err := c.Print(node)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(output, text)
+	return err
+}
+
+// Print returns the source of the node
+func (c *Config) Print(node ast.Node) (string, error) {
+	p := &printer{config: c, indent: c.Indent}
+	if p.indent == "" {
+		p.indent = "\t"
+	}
+
+	var text string
+	switch n := node.(type) {
+	case *ast.Program:
+		text = p.statements(n.Body)
+	case ast.Statement:
+		text = p.statement(n)
+	case ast.Expression:
+		text = p.expression(n, sequence)
+	default:
+		p.fail(n)
+	}
+	if p.err != nil {
+		return "", p.err
+	}
+
+	if _, isExpression := node.(ast.Expression); !isExpression && !c.Compact && text != "" {
+		text += "\n"
+	}
+
+	return text, nil
+}
+
+// String returns the source of the node printed with the default configuration, or the error
+func String(node ast.Node) string {
+	text, err := (&Config{}).Print(node)
+	if err != nil {
+		return err.Error()
+	}
+
+	return text
+}
+
+type printer struct {
+	config *Config
+	indent string
+	level  int
+	// noIn is set in the initializer of a for statement, where the in operator must be enclosed in parentheses
+	noIn bool
+	err  error
+}
+
+func (p *printer) fail(node ast.Node) {
+	if p.err == nil {
+		p.err = fmt.Errorf("Cannot print %T", node)
+	}
+}
+
+// space returns the optional space
+func (p *printer) space() string {
+	if p.config.Compact {
+		return ""
+	}
+
+	return " "
+}
+
+// newline returns a line break followed by the indentation of the current level
+func (p *printer) newline() string {
+	if p.config.Compact {
+		return ""
+	}
+
+	return "\n" + strings.Repeat(p.indent, p.level)
+}
+
+// cat concatenates the tokens, separated by a space where they would otherwise merge
+func cat(tokens ...string) string {
+	var builder strings.Builder
+	last := ""
+	for _, t := range tokens {
+		if t == "" {
+			continue
+		}
+		if last != "" && merges(last[len(last)-1], t[0]) {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(t)
+		last = t
+	}
+
+	return builder.String()
+}
+
+// merges returns true if the characters can not be adjacent without changing the tokens
+func merges(a, b byte) bool {
+	switch {
+	case identifierPart(a) && identifierPart(b):
+		return true
+	case a == '+' && b == '+', a == '-' && b == '-', a == '/' && b == '/':
+		return true
+	}
+
+	return false
+}
+
+func identifierPart(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// statements prints the statements on separate lines at the current level
+func (p *printer) statements(list []ast.Statement) string {
+	var lines []string
+	for _, statement := range list {
+		lines = append(lines, p.statement(statement))
+	}
+
+	return strings.Join(lines, p.newline())
+}
+
+// block prints the statements in braces, indented
+func (p *printer) block(list []ast.Statement) string {
+	if len(list) == 0 {
+		return "{}"
+	}
+
+	p.level++
+	text := "{" + p.newline() + p.statements(list)
+	p.level--
+
+	return text + p.newline() + "}"
+}
+
+// body prints the body of a compound statement, following its header
+func (p *printer) body(header string, body ast.Statement) string {
+	if block, ok := body.(*ast.BlockStatement); ok {
+		return header + p.space() + p.block(block.List)
+	}
+	if _, ok := body.(*ast.EmptyStatement); ok {
+		return header + ";"
+	}
+
+	p.level++
+	text := cat(header, p.newline(), p.statement(body))
+	p.level--
+
+	return text
+}
+
+func (p *printer) statement(statement ast.Statement) string {
+	sp := p.space()
+
+	switch s := statement.(type) {
+	case *ast.BlockStatement:
+		return p.block(s.List)
+	case *ast.BranchStatement:
+		if s.Label != nil {
+			return cat(s.Token.String(), s.Label.Name) + ";"
+		}
+		return s.Token.String() + ";"
+	case *ast.DebuggerStatement:
+		return "debugger;"
+	case *ast.DoWhileStatement:
+		text := p.body("do", s.Body)
+		if _, ok := s.Body.(*ast.BlockStatement); ok {
+			text += sp
+		} else {
+			text += p.newline()
+		}
+		return cat(text, "while") + sp + "(" + p.expression(s.Test, sequence) + ");"
+	case *ast.EmptyStatement:
+		return ";"
+	case *ast.ExpressionStatement:
+		text := p.expression(s.Expression, sequence)
+		// Statements starting with these are not expression statements
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "function") && len(text) > 8 && !identifierPart(text[8]) {
+			text = "(" + text + ")"
+		}
+		return text + ";"
+	case *ast.ForInStatement:
+		var into string
+		if _, ok := s.Into.(*ast.VariableExpression); ok {
+			into = cat("var", p.expressionText(s.Into))
+		} else {
+			into = p.expression(s.Into, call)
+		}
+		return p.body("for"+sp+"("+cat(into, "in", p.expression(s.Source, sequence))+")", s.Body)
+	case *ast.ForStatement:
+		p.noIn = true
+		initializer := p.forInitializer(s.Initializer)
+		p.noIn = false
+		header := "for" + sp + "(" + initializer + ";"
+		if s.Test != nil {
+			header += sp + p.expression(s.Test, sequence)
+		}
+		header += ";"
+		if s.Update != nil {
+			header += sp + p.expression(s.Update, sequence)
+		}
+		return p.body(header+")", s.Body)
+	case *ast.FunctionStatement:
+		return p.function(s.Function)
+	case *ast.IfStatement:
+		return p.ifStatement(s)
+	case *ast.LabelledStatement:
+		return s.Label.Name + ":" + sp + p.statement(s.Statement)
+	case *ast.ReturnStatement:
+		if s.Argument == nil {
+			return "return;"
+		}
+		return cat("return", sp, p.expression(s.Argument, sequence)) + ";"
+	case *ast.SwitchStatement:
+		return p.switchStatement(s)
+	case *ast.CaseStatement:
+		return p.caseClause(s)
+	case *ast.CatchStatement:
+		return p.catchClause(s)
+	case *ast.ThrowStatement:
+		return cat("throw", sp, p.expression(s.Argument, sequence)) + ";"
+	case *ast.TryStatement:
+		text := "try" + sp + p.statement(s.Body)
+		if s.Catch != nil {
+			text += sp + p.catchClause(s.Catch)
+		}
+		if s.Finally != nil {
+			text += sp + "finally" + sp + p.statement(s.Finally)
+		}
+		return text
+	case *ast.VariableStatement:
+		return p.variables(s.List) + ";"
+	case *ast.WhileStatement:
+		return p.body("while"+sp+"("+p.expression(s.Test, sequence)+")", s.Body)
+	case *ast.WithStatement:
+		return p.body("with"+sp+"("+p.expression(s.Object, sequence)+")", s.Body)
+	}
+
+	p.fail(statement)
+	return ""
+}
+
+// forInitializer prints the initializer of a for statement, a sequence of variables if they are declared
+func (p *printer) forInitializer(initializer ast.Expression) string {
+	if initializer == nil {
+		return ""
+	}
+
+	if s, ok := initializer.(*ast.SequenceExpression); ok {
+		if len(s.Sequence) == 0 {
+			return ""
+		}
+		if _, ok := s.Sequence[0].(*ast.VariableExpression); ok {
+			return p.variables(s.Sequence)
+		}
+		if len(s.Sequence) == 1 {
+			return p.expression(s.Sequence[0], sequence)
+		}
+	}
+
+	return p.expression(initializer, sequence)
+}
+
+// variables prints a var declaration of the variable expressions
+func (p *printer) variables(list []ast.Expression) string {
+	var declarations []string
+	for _, expression := range list {
+		declarations = append(declarations, p.expressionText(expression))
+	}
+
+	return cat("var", strings.Join(declarations, ","+p.space()))
+}
+
+func (p *printer) ifStatement(s *ast.IfStatement) string {
+	sp := p.space()
+	header := "if" + sp + "(" + p.expression(s.Test, sequence) + ")"
+
+	consequent := s.Consequent
+	if _, ok := consequent.(*ast.BlockStatement); !ok && s.Alternate != nil && danglingElse(consequent) {
+		// The else would belong to the if statement ending the consequent
+		consequent = &ast.BlockStatement{List: []ast.Statement{consequent}}
+	}
+	text := p.body(header, consequent)
+	if s.Alternate == nil {
+		return text
+	}
+
+	if _, ok := consequent.(*ast.BlockStatement); ok {
+		text += sp
+	} else {
+		text += p.newline()
+	}
+	if alternate, ok := s.Alternate.(*ast.IfStatement); ok {
+		return cat(text+"else", " ", p.ifStatement(alternate))
+	}
+
+	return text + p.body("else", s.Alternate)
+}
+
+// danglingElse returns true if the statement ends with an if statement without else
+func danglingElse(statement ast.Statement) bool {
+	switch s := statement.(type) {
+	case *ast.IfStatement:
+		if s.Alternate == nil {
+			return true
+		}
+		return danglingElse(s.Alternate)
+	case *ast.ForStatement:
+		return danglingElse(s.Body)
+	case *ast.ForInStatement:
+		return danglingElse(s.Body)
+	case *ast.WhileStatement:
+		return danglingElse(s.Body)
+	case *ast.WithStatement:
+		return danglingElse(s.Body)
+	case *ast.LabelledStatement:
+		return danglingElse(s.Statement)
+	}
+
+	return false
+}
+
+func (p *printer) switchStatement(s *ast.SwitchStatement) string {
+	sp := p.space()
+	text := "switch" + sp + "(" + p.expression(s.Discriminant, sequence) + ")" + sp + "{"
+
+	p.level++
+	for _, c := range s.Body {
+		text += p.newline() + p.caseClause(c)
+	}
+	p.level--
+
+	if len(s.Body) > 0 {
+		text += p.newline()
+	}
+
+	return text + "}"
+}
+
+// caseClause prints the label of the clause followed by its statements, indented on their own lines
+func (p *printer) caseClause(c *ast.CaseStatement) string {
+	text := "default:"
+	if c.Test != nil {
+		text = cat("case", p.space(), p.expression(c.Test, sequence)) + ":"
+	}
+
+	p.level++
+	for _, statement := range c.Consequent {
+		text += p.newline() + p.statement(statement)
+	}
+	p.level--
+
+	return text
+}
+
+// catchClause prints the catch clause of a try statement
+func (p *printer) catchClause(c *ast.CatchStatement) string {
+	sp := p.space()
+	return "catch" + sp + "(" + c.Parameter.Name + ")" + sp + p.statement(c.Body)
+}
+
+func (p *printer) function(f *ast.FunctionLiteral) string {
+	text := "function"
+	if f.Name != nil {
+		text = cat(text, f.Name.Name)
+	}
+
+	return text + p.parameters(f) + p.space() + p.functionBody(f)
+}
+
+// parameters prints the parameters of the function in parentheses
+func (p *printer) parameters(f *ast.FunctionLiteral) string {
+	var names []string
+	if f.ParameterList != nil {
+		for _, parameter := range f.ParameterList.List {
+			names = append(names, parameter.Name)
+		}
+	}
+
+	return "(" + strings.Join(names, ","+p.space()) + ")"
+}
+
+func (p *printer) functionBody(f *ast.FunctionLiteral) string {
+	// The in operator is allowed again in functions of for initializers
+	noIn := p.noIn
+	p.noIn = false
+	defer func() { p.noIn = noIn }()
+
+	if block, ok := f.Body.(*ast.BlockStatement); ok {
+		return p.block(block.List)
+	}
+
+	return p.block([]ast.Statement{f.Body})
+}
+
+// expression prints the expression, in parentheses if it binds looser than the precedence
+func (p *printer) expression(expression ast.Expression, precedence int) string {
+	own := precedenceOf(expression)
+	if own >= precedence && !(p.noIn && own <= relational && containsIn(expression)) {
+		return p.expressionText(expression)
+	}
+
+	noIn := p.noIn
+	p.noIn = false
+	text := p.expressionText(expression)
+	p.noIn = noIn
+
+	return "(" + text + ")"
+}
+
+// precedenceOf returns the precedence of the expression
+func precedenceOf(expression ast.Expression) int {
+	switch e := expression.(type) {
+	case *ast.AssignExpression:
+		return assignment
+	case *ast.BinaryExpression:
+		return binaryPrecedence(e.Operator)
+	case *ast.CallExpression:
+		return call
+	case *ast.ConditionalExpression:
+		return conditional
+	case *ast.BracketExpression, *ast.DotExpression, *ast.NewExpression:
+		return member
+	case *ast.NumberLiteral:
+		if strings.HasPrefix(number(e), "-") {
+			return unary
+		}
+	case *ast.SequenceExpression:
+		return sequence
+	case *ast.UnaryExpression:
+		if e.Postfix {
+			return postfix
+		}
+		return unary
+	case *ast.VariableExpression:
+		if e.Initializer != nil {
+			return assignment
+		}
+	}
+
+	return primary
+}
+
+// containsIn returns true if the expression has an in operator outside of parentheses
+func containsIn(expression ast.Expression) bool {
+	switch e := expression.(type) {
+	case *ast.BinaryExpression:
+		return e.Operator == token.IN || containsIn(e.Left) || containsIn(e.Right)
+	case *ast.AssignExpression:
+		return containsIn(e.Right)
+	case *ast.ConditionalExpression:
+		return containsIn(e.Test) || containsIn(e.Consequent) || containsIn(e.Alternate)
+	case *ast.SequenceExpression:
+		for _, item := range e.Sequence {
+			if containsIn(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// expressionText returns the source of the expression
+func (p *printer) expressionText(expression ast.Expression) string {
+	sp := p.space()
+
+	switch e := expression.(type) {
+	case *ast.ArrayLiteral:
+		var items []string
+		for _, item := range e.Value {
+			if item == nil {
+				items = append(items, "")
+			} else if _, ok := item.(*ast.EmptyExpression); ok {
+				items = append(items, "")
+			} else {
+				items = append(items, p.expression(item, assignment))
+			}
+		}
+		text := strings.Join(items, ","+sp)
+		if len(items) > 0 && items[len(items)-1] == "" {
+			// A trailing hole needs its own comma
+			text += ","
+		}
+		return "[" + text + "]"
+	case *ast.AssignExpression:
+		operator := "="
+		if e.Operator != token.ASSIGN {
+			operator = e.Operator.String() + "="
+		}
+		return cat(p.expression(e.Left, call), sp+operator+sp, p.expression(e.Right, assignment))
+	case *ast.BinaryExpression:
+		precedence := binaryPrecedence(e.Operator)
+		return cat(p.expression(e.Left, precedence), sp, e.Operator.String(), sp, p.expression(e.Right, precedence+1))
+	case *ast.BooleanLiteral:
+		if e.Value {
+			return "true"
+		}
+		return "false"
+	case *ast.BracketExpression:
+		return p.object(e.Left) + "[" + p.expression(e.Member, sequence) + "]"
+	case *ast.CallExpression:
+		return p.expression(e.Callee, call) + p.arguments(e.ArgumentList)
+	case *ast.ConditionalExpression:
+		return p.expression(e.Test, logicalOr) + sp + "?" + sp + p.expression(e.Consequent, assignment) + sp + ":" + sp +
+			p.expression(e.Alternate, assignment)
+	case *ast.DotExpression:
+		return p.object(e.Left) + "." + e.Identifier.Name
+	case *ast.EmptyExpression:
+		// Holes of array literals
+		return ""
+	case *ast.FunctionLiteral:
+		return p.function(e)
+	case *ast.Identifier:
+		return e.Name
+	case *ast.NewExpression:
+		callee := p.expression(e.Callee, member)
+		if _, isCall := e.Callee.(*ast.CallExpression); !isCall && callChain(e.Callee) {
+			// A call in the callee would take the arguments of new
+			callee = "(" + callee + ")"
+		}
+		return cat("new", sp, callee) + p.arguments(e.ArgumentList)
+	case *ast.NullLiteral:
+		return "null"
+	case *ast.NumberLiteral:
+		return number(e)
+	case *ast.ObjectLiteral:
+		return p.objectLiteral(e)
+	case *ast.RegExpLiteral:
+		if e.Literal != "" {
+			return e.Literal
+		}
+		return "/" + e.Pattern + "/" + e.Flags
+	case *ast.SequenceExpression:
+		var items []string
+		for _, item := range e.Sequence {
+			items = append(items, p.expression(item, assignment))
+		}
+		return strings.Join(items, ","+sp)
+	case *ast.StringLiteral:
+		if e.Literal != "" {
+			return e.Literal
+		}
+		return quote(e.Value)
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.UnaryExpression:
+		if e.Postfix {
+			return p.expression(e.Operand, call) + e.Operator.String()
+		}
+		operator := e.Operator.String()
+		switch e.Operator {
+		case token.TYPEOF, token.VOID, token.DELETE:
+			return cat(operator, sp, p.expression(e.Operand, unary))
+		}
+		return cat(operator, p.expression(e.Operand, unary))
+	case *ast.VariableExpression:
+		if e.Initializer == nil {
+			return e.Name
+		}
+		return e.Name + sp + "=" + sp + p.expression(e.Initializer, assignment)
+	}
+
+	p.fail(expression)
+	return ""
+}
+
+// object prints the object of a member expression
+func (p *printer) object(expression ast.Expression) string {
+	text := p.expression(expression, call)
+	if _, ok := expression.(*ast.NumberLiteral); ok && !strings.HasPrefix(text, "(") && !strings.ContainsAny(text, ".eExX") {
+		// The dot would be the decimal point of the number
+		return "(" + text + ")"
+	}
+
+	return text
+}
+
+// callChain returns true if the member expression contains a call
+func callChain(expression ast.Expression) bool {
+	switch e := expression.(type) {
+	case *ast.CallExpression:
+		return true
+	case *ast.DotExpression:
+		return callChain(e.Left)
+	case *ast.BracketExpression:
+		return callChain(e.Left)
+	}
+
+	return false
+}
+
+func (p *printer) arguments(list []ast.Expression) string {
+	var arguments []string
+	for _, argument := range list {
+		arguments = append(arguments, p.expression(argument, assignment))
+	}
+
+	return "(" + strings.Join(arguments, ","+p.space()) + ")"
+}
+
+func (p *printer) objectLiteral(o *ast.ObjectLiteral) string {
+	if len(o.Value) == 0 {
+		return "{}"
+	}
+
+	sp := p.space()
+	noIn := p.noIn
+	p.noIn = false
+	p.level++
+	var properties []string
+	for _, property := range o.Value {
+		key := propertyKey(property.Key)
+		switch property.Kind {
+		case "get", "set":
+			f, ok := property.Value.(*ast.FunctionLiteral)
+			if !ok {
+				p.fail(property.Value)
+				continue
+			}
+			properties = append(properties, p.newline()+property.Kind+" "+key+p.parameters(f)+sp+p.functionBody(f))
+		default:
+			properties = append(properties, p.newline()+key+":"+sp+p.expression(property.Value, assignment))
+		}
+	}
+	p.level--
+	p.noIn = noIn
+
+	return "{" + strings.Join(properties, ",") + p.newline() + "}"
+}
+
+// propertyKey returns the key as an identifier or number if possible, else as a string
+func propertyKey(key string) string {
+	if identifier(key) {
+		return key
+	}
+	if value := constant.NewString(key).ToNumber(); constant.NewNumber(value).ToString() == key && !strings.HasPrefix(key, "-") && key != "NaN" && key != "Infinity" {
+		return key
+	}
+
+	return quote(key)
+}
+
+// identifier returns true if the text is an identifier name
+func identifier(text string) bool {
+	if text == "" || text[0] >= '0' && text[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(text); i++ {
+		if !identifierPart(text[i]) || text[i] == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// number returns the literal of the number, formatted from its value if the literal is not known
+func number(n *ast.NumberLiteral) string {
+	if n.Literal != "" {
+		return n.Literal
+	}
+
+	switch v := n.Value.(type) {
+	case int64:
+		return fmt.Sprint(v)
+	case float64:
+		return constant.NewNumber(v).ToString()
+	}
+
+	return fmt.Sprint(n.Value)
+}
+
+// quote returns the text as a double quoted string literal
+func quote(text string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		case '\v':
+			builder.WriteString(`\v`)
+		default:
+			if r < 0x20 || r == 0x2028 || r == 0x2029 {
+				fmt.Fprintf(&builder, `\u%04x`, r)
+			} else {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
+
+// binaryPrecedence returns the precedence of the binary operator
+func binaryPrecedence(operator token.Token) int {
+	switch operator {
+	case token.LOGICAL_OR:
+		return logicalOr
+	case token.LOGICAL_AND:
+		return logicalAnd
+	case token.OR:
+		return bitwiseOr
+	case token.EXCLUSIVE_OR:
+		return bitwiseXor
+	case token.AND:
+		return bitwiseAnd
+	case token.EQUAL, token.NOT_EQUAL, token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		return equality
+	case token.LESS, token.GREATER, token.LESS_OR_EQUAL, token.GREATER_OR_EQUAL, token.INSTANCEOF, token.IN:
+		return relational
+	case token.SHIFT_LEFT, token.SHIFT_RIGHT, token.UNSIGNED_SHIFT_RIGHT:
+		return shift
+	case token.PLUS, token.MINUS:
+		return additive
+	}
+
+	return multiplicative
+}
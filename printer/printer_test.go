@@ -0,0 +1,135 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/robertkrimen/otto/token"
+)
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`var a=1,b`, "var a = 1, b;\n"},
+		{`a = b ? c : d, e`, "a = b ? c : d, e;\n"},
+		{`(a + b) * c - (d - e) - f`, "(a + b) * c - (d - e) - f;\n"},
+		{`a = (b, c); x = (y = z)`, "a = (b, c);\nx = y = z;\n"},
+		{`(a ? b : c) ? d : e ? f : g`, "(a ? b : c) ? d : e ? f : g;\n"},
+		{`!(a && b) || typeof c === "x"`, "!(a && b) || typeof c === \"x\";\n"},
+		{`a++ + ++b - -c - (-d)`, "a++ + ++b - -c - -d;\n"},
+		{`- -a; + +b; -(--c)`, "- -a;\n+ +b;\n- --c;\n"},
+		{`new (f())(); new (a.b().c)(); new a.b.c(); new (new F)()`, "new (f())();\nnew (a.b().c)();\nnew a.b.c();\nnew new F()();\n"},
+		{`(function() {})(); (function f() { return 1 }())`, "(function() {}());\n(function f() {\n\treturn 1;\n}());\n"},
+		{`({}).x; ({a: 1, "b c": 2, 3: [1, , 2, ,]})`, "({}.x);\n({\n\ta: 1,\n\t\"b c\": 2,\n\t3: [1, , 2, ,]\n});\n"},
+		{`1..x; 1.5.x; (1).y`, "1..x;\n1.5.x;\n(1).y;\n"},
+		{`o = {get a() { return 1 }, set a(v) {}}`, "o = {\n\tget a() {\n\t\treturn 1;\n\t},\n\tset a(v) {}\n};\n"},
+		{`if (a) b(); else if (c) { d() } else e()`, "if (a)\n\tb();\nelse if (c) {\n\td();\n} else\n\te();\n"},
+		{`if (a) { if (b) c() } else d()`, "if (a) {\n\tif (b)\n\t\tc();\n} else\n\td();\n"},
+		{`for (var i = 0, n = ("a" in o); i < n; i++) ;`, "for (var i = 0, n = (\"a\" in o); i < n; i++);\n"},
+		{`for (x = ("a" in o);;) {} for (;;) break`, "for ((x = \"a\" in o);;) {}\nfor (;;)\n\tbreak;\n"},
+		{`for (var k in o) { continue } for (a.b in o) {}`, "for (var k in o) {\n\tcontinue;\n}\nfor (a.b in o) {}\n"},
+		{`do a(); while (b); do { c() } while (d)`, "do\n\ta();\nwhile (b);\ndo {\n\tc();\n} while (d);\n"},
+		{`outer: while (a) { break outer }`, "outer: while (a) {\n\tbreak outer;\n}\n"},
+		{`switch (a) { case 1: b(); break; default: c() }`, "switch (a) {\n\tcase 1:\n\t\tb();\n\t\tbreak;\n\tdefault:\n\t\tc();\n}\n"},
+		{`try { a() } catch (e) { throw e } finally { debugger }`, "try {\n\ta();\n} catch (e) {\n\tthrow e;\n} finally {\n\tdebugger;\n}\n"},
+		{`with (o) { x = /a+b/g.test(s) }`, "with (o) {\n\tx = /a+b/g.test(s);\n}\n"},
+		{`delete a[b]; void 0; a in b; a instanceof B`, "delete a[b];\nvoid 0;\na in b;\na instanceof B;\n"},
+		{`a += 1; b >>>= 2; c = d || (e && f)`, "a += 1;\nb >>>= 2;\nc = d || e && f;\n"},
+		{`function f(a, b) { function g() {} return a }`, "function f(a, b) {\n\tfunction g() {}\n\treturn a;\n}\n"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		result := String(program)
+		if result != test.expected {
+			t.Errorf("[%v] Failed, source not correct\n%v\n%v", i, test.expected, result)
+			continue
+		}
+
+		// The printed source must print the same
+		reparsed, err := parser.ParseFile(nil, "", result, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, printed source is not valid, %v", i, err)
+			continue
+		}
+		if again := String(reparsed); again != result {
+			t.Errorf("[%v] Failed, printing is not stable\n%v\n%v", i, result, again)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`var a = 1, b = typeof c`, "var a=1,b=typeof c;"},
+		{`a + +b; a - -b; a + ++b; a-- - b; a / /x/`, "a+ +b;a- -b;a+ ++b;a-- -b;a/ /x/;"},
+		{`function f() { if (a) return b; else c() }`, "function f(){if(a)return b;else c();}"},
+		{`function f(a) { return a in o } f(1)`, "function f(a){return a in o;}f(1);"},
+		{`do x(); while (y)`, "do x();while(y);"},
+		{`o = {a: [1, 2], "b": "c"}`, `o={a:[1,2],b:"c"};`},
+	}
+
+	config := &Config{Compact: true}
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		result, err := config.Print(program)
+		if err != nil || result != test.expected {
+			t.Errorf("[%v] Failed, source not correct, %v\n%v\n%v", i, err, test.expected, result)
+		}
+		if _, err := parser.ParseFile(nil, "", result, 0); err != nil {
+			t.Errorf("[%v] Failed, printed source is not valid, %v", i, err)
+		}
+	}
+}
+
+func TestPrintNodes(t *testing.T) {
+	a, b, c := &ast.Identifier{Name: "a"}, &ast.Identifier{Name: "b"}, &ast.Identifier{Name: "c"}
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		// Nodes built without parentheses
+		{&ast.BinaryExpression{Operator: token.MULTIPLY, Left: &ast.BinaryExpression{Operator: token.PLUS, Left: a, Right: b}, Right: c}, "(a + b) * c"},
+		{&ast.BinaryExpression{Operator: token.MINUS, Left: a, Right: &ast.BinaryExpression{Operator: token.MINUS, Left: b, Right: c}}, "a - (b - c)"},
+		{&ast.UnaryExpression{Operator: token.MINUS, Operand: &ast.NumberLiteral{Value: int64(-1)}}, "- -1"},
+		{&ast.DotExpression{Left: &ast.NumberLiteral{Value: int64(-2)}, Identifier: b}, "(-2).b"},
+		{&ast.StringLiteral{Value: "a\"b\n\u2028"}, `"a\"b\n\u2028"`},
+		{&ast.NumberLiteral{Value: 1e21}, "1e+21"},
+		{&ast.ExpressionStatement{Expression: &ast.CallExpression{Callee: &ast.FunctionLiteral{ParameterList: &ast.ParameterList{}, Body: &ast.BlockStatement{}}}}, "(function() {}());\n"},
+		{&ast.IfStatement{Test: a, Consequent: &ast.IfStatement{Test: b, Consequent: &ast.ExpressionStatement{Expression: c}}, Alternate: &ast.EmptyStatement{}}, "if (a) {\n\tif (b)\n\t\tc;\n} else;\n"},
+		{&ast.CaseStatement{Test: a, Consequent: []ast.Statement{&ast.ExpressionStatement{Expression: b}, &ast.BranchStatement{Token: token.BREAK}}}, "case a:\n\tb;\n\tbreak;\n"},
+		{&ast.CaseStatement{}, "default:\n"},
+		{&ast.CatchStatement{Parameter: a, Body: &ast.BlockStatement{List: []ast.Statement{&ast.ExpressionStatement{Expression: b}}}}, "catch (a) {\n\tb;\n}\n"},
+		{&ast.EmptyExpression{}, ""},
+	}
+
+	for i, test := range tests {
+		if result := String(test.node); result != test.expected {
+			t.Errorf("[%v] Failed, source not correct\n%v\n%v", i, test.expected, result)
+		}
+	}
+
+	if _, err := (&Config{}).Print(&ast.BadExpression{}); err == nil {
+		t.Errorf("Failed, expected an error for bad expressions")
+	}
+
+	config := &Config{Indent: "  "}
+	if result, _ := config.Print(&ast.BlockStatement{List: []ast.Statement{&ast.BlockStatement{List: []ast.Statement{&ast.EmptyStatement{}}}}}); result != "{\n  {\n    ;\n  }\n}\n" {
+		t.Errorf("Failed, indentation not correct\n%v", result)
+	}
+}
@@ -0,0 +1,141 @@
+package walker
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// Binding identifies a declared variable by its scope and name
+type Binding struct {
+	// Scope is the node declaring the variable, nil for undeclared globals
+	Scope ast.Node
+	Name  string
+	// Unknown is true if the variable is resolved in a dynamic scope, which is then the Scope
+	Unknown bool
+}
+
+// ResolveBinding returns the binding the name refers to from the current node
+func ResolveBinding(metadata []Metadata, name string) Binding {
+	i, unknown := resolveVariable(metadata, name)
+	if i < 0 {
+		return Binding{Name: name}
+	}
+
+	return Binding{Scope: metadata[i].Node(), Name: name, Unknown: unknown}
+}
+
+// Reference is an occurrence of a variable name in the source
+type Reference struct {
+	Idx     file.Idx
+	Name    string
+	Binding Binding
+	// Access is zero for declarations
+	Access Access
+	// Scopes lists the enclosing scopes, including dynamic scopes, the innermost last
+	Scopes []ast.Node
+}
+
+// IsDeclaration returns true if the reference declares the variable
+func (r *Reference) IsDeclaration() bool {
+	return r.Access == 0
+}
+
+// ReferenceCollector collects declarations and references of variables found by the walker
+type ReferenceCollector struct {
+	References []*Reference
+	// Dynamic lists the dynamic scopes
+	Dynamic map[ast.Node]bool
+	// Metadata is the metadata of the walked node, holding the file and the source map locating the references
+	Metadata Metadata
+}
+
+// NewReferenceCollector returns a new instance
+func NewReferenceCollector() *ReferenceCollector {
+	return &ReferenceCollector{
+		Dynamic: map[ast.Node]bool{},
+	}
+}
+
+// Hook returns the hook collecting the references
+func (c *ReferenceCollector) Hook() *Hook {
+	return &Hook{
+		OnNode:     c.onNode,
+		OnFinished: c.onFinished,
+	}
+}
+
+// Binding returns the references of the given binding
+func (c *ReferenceCollector) Binding(binding Binding) []*Reference {
+	var references []*Reference
+	for _, reference := range c.References {
+		if reference.Binding == binding {
+			references = append(references, reference)
+		}
+	}
+
+	return references
+}
+
+// At returns the reference covering the given index, nil if none
+func (c *ReferenceCollector) At(idx file.Idx) *Reference {
+	for _, reference := range c.References {
+		if idx >= reference.Idx && int(idx) < int(reference.Idx)+len(reference.Name) {
+			return reference
+		}
+	}
+
+	return nil
+}
+
+func (c *ReferenceCollector) onNode(node ast.Node, metadata []Metadata) error {
+	switch n := node.(type) {
+	case *ast.VariableExpression:
+		c.add(metadata, n.Idx, n.Name, 0)
+	case *ast.Identifier:
+		access := IdentifierAccess(metadata)
+		if access != 0 {
+			c.add(metadata, n.Idx, n.Name, access)
+			return nil
+		}
+
+		switch p := ParentMetadata(metadata).Node().(type) {
+		case *ast.FunctionLiteral:
+			// The name of a function statement is declared in the enclosing scope
+			if _, isStatement := metadata[len(metadata)-3].Node().(*ast.FunctionStatement); isStatement && p.Name == n {
+				c.add(metadata[:len(metadata)-2], n.Idx, n.Name, 0)
+				return nil
+			}
+			c.add(metadata, n.Idx, n.Name, 0)
+		case *ast.CatchStatement:
+			c.add(metadata, n.Idx, n.Name, 0)
+		}
+	}
+
+	return nil
+}
+
+func (c *ReferenceCollector) onFinished(node ast.Node, metadata Metadata) error {
+	c.Metadata = metadata
+
+	return nil
+}
+
+func (c *ReferenceCollector) add(metadata []Metadata, idx file.Idx, name string, access Access) {
+	reference := &Reference{
+		Idx:     idx,
+		Name:    name,
+		Binding: ResolveBinding(metadata, name),
+		Access:  access,
+	}
+
+	for i, md := range metadata {
+		if IsDynamicScope(metadata, i) {
+			c.Dynamic[md.Node()] = true
+			reference.Scopes = append(reference.Scopes, md.Node())
+		} else if _, ok := md[Vars]; ok {
+			reference.Scopes = append(reference.Scopes, md.Node())
+		}
+	}
+
+	c.References = append(c.References, reference)
+}
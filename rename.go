@@ -0,0 +1,140 @@
+package walker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// IsIdentifierName returns true if the name can be used as a variable name
+func IsIdentifierName(name string) bool {
+	if !identifierPattern.MatchString(name) {
+		return false
+	}
+
+	switch name {
+	case "null", "true", "false", "undefined", "NaN", "Infinity", "eval", "arguments":
+		return false
+	}
+
+	tkn, _ := token.IsKeyword(name)
+	return tkn == 0
+}
+
+// Rename returns the edits renaming the variable declared or referenced at the given index
+func Rename(program *ast.Program, idx file.Idx, name string) ([]TextEdit, error) {
+	references := NewReferenceCollector()
+	visitor := &VisitorImpl{}
+	visitor.AddHook(references.Hook())
+	NewWalker(visitor).Begin(program)
+
+	reference := references.At(idx)
+	if reference == nil {
+		return nil, fmt.Errorf("No variable at %v", idx)
+	}
+
+	return references.Rename(reference.Binding, name)
+}
+
+// Rename returns the edits renaming the declaration and every reference of the binding.
+// The rename is refused if the new name collides with or is captured by another variable,
+// or if the binding is visible in a dynamic scope, i.e. a with statement or a scope calling eval.
+func (c *ReferenceCollector) Rename(binding Binding, name string) ([]TextEdit, error) {
+	if binding.Unknown {
+		return nil, fmt.Errorf("Cannot rename %v, it is resolved in a dynamic scope", binding.Name)
+	}
+	if binding.Scope == nil {
+		return nil, fmt.Errorf("Cannot rename undeclared global %v", binding.Name)
+	}
+	if !IsIdentifierName(name) {
+		return nil, fmt.Errorf("Invalid variable name %v", name)
+	}
+
+	references := c.Binding(binding)
+	if len(references) == 0 {
+		return nil, fmt.Errorf("Unknown variable %v", binding.Name)
+	}
+	if name == binding.Name {
+		return nil, nil
+	}
+
+	// Scopes declaring the new name
+	declaring := map[ast.Node]bool{}
+	for _, reference := range c.References {
+		if reference.Name == name && reference.IsDeclaration() {
+			declaring[reference.Binding.Scope] = true
+		}
+	}
+	if declaring[binding.Scope] {
+		return nil, fmt.Errorf("Cannot rename %v, %v is already declared in the same scope", binding.Name, name)
+	}
+
+	var edits []TextEdit
+	for _, reference := range references {
+		for _, scope := range innerScopes(reference.Scopes, binding.Scope) {
+			if c.Dynamic[scope] {
+				return nil, fmt.Errorf("Cannot rename %v, it is used in a dynamic scope", binding.Name)
+			}
+			if scope != binding.Scope && declaring[scope] {
+				return nil, fmt.Errorf("Cannot rename %v, %v would be captured by an inner declaration", binding.Name, name)
+			}
+		}
+
+		edits = append(edits, TextEdit{
+			Idx0: reference.Idx,
+			Idx1: reference.Idx + file.Idx(len(binding.Name)),
+			Text: name,
+		})
+	}
+
+	for _, reference := range c.References {
+		inside := indexOfScope(reference.Scopes, binding.Scope)
+
+		// Unresolved references with either name could refer to the renamed variable
+		if reference.Binding.Unknown && inside >= 0 && (reference.Name == name || reference.Name == binding.Name) {
+			return nil, fmt.Errorf("Cannot rename %v, %v at %v is resolved in a dynamic scope", binding.Name, reference.Name, reference.Idx)
+		}
+
+		// References of an outer variable with the new name must not be captured by the renamed variable
+		if reference.Name != name || reference.Binding.Scope == binding.Scope {
+			continue
+		}
+
+		if inside >= 0 && indexOfScope(reference.Scopes, reference.Binding.Scope) < inside {
+			return nil, fmt.Errorf("Cannot rename %v, it would capture the reference of %v at %v", binding.Name, name, reference.Idx)
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Idx0 < edits[j].Idx0
+	})
+
+	return edits, nil
+}
+
+// innerScopes returns the scopes from the given scope and inwards, nil if the scope is not present
+func innerScopes(scopes []ast.Node, scope ast.Node) []ast.Node {
+	i := indexOfScope(scopes, scope)
+	if i < 0 {
+		return nil
+	}
+
+	return scopes[i:]
+}
+
+// indexOfScope returns the position of the scope in the list, -1 if not present
+func indexOfScope(scopes []ast.Node, scope ast.Node) int {
+	for i, s := range scopes {
+		if s == scope {
+			return i
+		}
+	}
+
+	return -1
+}
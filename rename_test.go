@@ -0,0 +1,63 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		src    string
+		at     string
+		name   string
+		result string
+	}{
+		{`var a = 1; a++; f(a)`, `a++`, `b`, `var b = 1; b++; f(b)`},
+		{`function f(a) { return a.a + g(a) }`, `a)`, `x`, `function f(x) { return x.a + g(x) }`},
+		{`var a; function f(a) { return a }`, `a;`, `b`, `var b; function f(a) { return a }`},
+		{`function f() {} f(); function g() { return f }`, `f()`, `h`, `function h() {} h(); function g() { return h }`},
+		{`try {} catch (e) { log(e) }`, `e)`, `err`, `try {} catch (err) { log(err) }`},
+		{`var a, b`, `a,`, `b`, ``},
+		{`var a; function f(b) { return a }`, `a;`, `b`, ``},
+		{`var a; function f() { return b + a }`, `a;`, `b`, ``},
+		{`var a; with (o) { a }`, `a;`, `b`, ``},
+		{`var a; with (o) { b }`, `a;`, `b`, ``},
+		{`var a; with (o) { c }; a`, `a;`, `b`, `var b; with (o) { c }; b`},
+		{`function f(a) { eval(s); return a }`, `a)`, `b`, ``},
+		{`a = 1`, `a`, `b`, ``},
+		{`var a`, `a`, `var`, ``},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		idx := file.Idx(strings.Index(test.src, test.at) + program.File.Base())
+		edits, err := Rename(program, idx, test.name)
+		if test.result == "" {
+			if err == nil {
+				t.Errorf("[%v] Failed, expected rename to be refused", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		result := test.src
+		for j := len(edits) - 1; j >= 0; j-- {
+			start, end := edits[j].Offsets(program.File)
+			result = result[:start] + edits[j].Text + result[end:]
+		}
+		if test.result != result {
+			t.Errorf("[%v] Failed, result not correct, %v != %v", i, test.result, result)
+		}
+	}
+}
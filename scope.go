@@ -0,0 +1,117 @@
+package walker
+
+import (
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+// Unknown is the index returned when a variable is resolved in a dynamic scope
+const Unknown file.Idx = -2
+
+// Access describes how an identifier is used
+type Access int
+
+const (
+	Read Access = 1 << iota
+	Write
+)
+
+// FindVariableMetadata returns the metadata up to and including the scope declaring the variable.
+// Dynamic scopes are not considered, see ResolveBinding.
+func FindVariableMetadata(metadata []Metadata, name string) []Metadata {
+	for i := len(metadata) - 1; i >= 0; i-- {
+		vars, ok := metadata[i][Vars].(Variables)
+		if !ok {
+			continue
+		}
+
+		if _, found := vars[name]; found {
+			return metadata[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// IsDynamicScope returns true if the i'th metadata is a dynamic scope for the nodes below it.
+// Functions and programs calling eval are dynamic, as are the bodies of with statements.
+func IsDynamicScope(metadata []Metadata, i int) bool {
+	dynamic, _ := metadata[i][Dynamic].(bool)
+	if !dynamic {
+		return false
+	}
+
+	// The object of a with statement is not affected
+	if with, ok := metadata[i].Node().(*ast.WithStatement); ok {
+		return i+1 < len(metadata) && metadata[i+1].Node() == with.Body
+	}
+
+	return true
+}
+
+// resolveVariable returns the position of the metadata declaring the variable, -1 if not found.
+// If a dynamic scope is met first, its position is returned and unknown is true.
+func resolveVariable(metadata []Metadata, name string) (i int, unknown bool) {
+	for i = len(metadata) - 1; i >= 0; i-- {
+		vars, ok := metadata[i][Vars].(Variables)
+		if ok {
+			if _, found := vars[name]; found {
+				return i, false
+			}
+		}
+
+		if IsDynamicScope(metadata, i) {
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+// DynamicScope returns the innermost dynamic scope enclosing the current node, nil if none
+func DynamicScope(metadata []Metadata) ast.Node {
+	for i := len(metadata) - 1; i >= 0; i-- {
+		if IsDynamicScope(metadata, i) {
+			return metadata[i].Node()
+		}
+	}
+
+	return nil
+}
+
+// IdentifierAccess returns how the current identifier is accessed.
+// Zero is returned if the identifier is not a variable reference,
+// e.g. a property name, a label or the name of a declaration.
+func IdentifierAccess(metadata []Metadata) Access {
+	identifier, ok := CurrentMetadata(metadata).Node().(*ast.Identifier)
+	if !ok {
+		return 0
+	}
+
+	switch p := ParentMetadata(metadata).Node().(type) {
+	case *ast.DotExpression:
+		if p.Identifier == identifier {
+			return 0
+		}
+	case *ast.LabelledStatement, *ast.BranchStatement, *ast.FunctionLiteral, *ast.CatchStatement:
+		return 0
+	case *ast.AssignExpression:
+		if p.Left == identifier {
+			if p.Operator == token.ASSIGN {
+				return Write
+			}
+			return Read | Write
+		}
+	case *ast.UnaryExpression:
+		if p.Operator == token.INCREMENT || p.Operator == token.DECREMENT {
+			return Read | Write
+		}
+	case *ast.ForInStatement:
+		if p.Into == identifier {
+			return Write
+		}
+	}
+
+	return Read
+}
@@ -0,0 +1,181 @@
+package walker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiGutter = "\x1b[1;34m"
+	ansiCaret  = "\x1b[1;31m"
+)
+
+// SnippetOptions configures the rendering of snippets
+type SnippetOptions struct {
+	// Context is the number of lines shown before and after the node
+	Context int
+	// Color emits ANSI escape codes for the gutter and the underline
+	Color bool
+}
+
+// Snippet renders the source of the node with line numbers and an underline of its range.
+// The original source is rendered if the node is mapped by the source map, which includes the content of the source.
+// The empty string is returned if the file of the walked node is not known.
+func (w *Walker) Snippet(node ast.Node, options SnippetOptions) string {
+	f := w.file()
+	if f == nil {
+		return ""
+	}
+	idx0, idx1 := nodeRange(f, node)
+	if w.SourceMap != nil {
+		if snippet := w.SourceMap.snippet(f, idx0, idx1, options); snippet != "" {
+			return snippet
+		}
+	}
+
+	return RenderSnippet(f, idx0, idx1, options)
+}
+
+// file returns the file attached to the walker or of the walked program, nil if not known
+func (w *Walker) file() *file.File {
+	if w.File != nil {
+		return w.File
+	}
+	if w.program != nil {
+		return w.program.File
+	}
+	if program, ok := w.Root.(*ast.Program); ok {
+		return program.File
+	}
+
+	return nil
+}
+
+// RenderSnippet renders the lines of the file covering the range, underlining it with ^~~~, e.g.
+//
+//	 --> script.js:2:5
+//	  |
+//	2 | if (a) {
+//	  |     ^
+func RenderSnippet(f *file.File, idx0, idx1 file.Idx, options SnippetOptions) string {
+	source := f.Source()
+	start := clamp(int(idx0)-f.Base(), 0, len(source))
+	end := clamp(int(idx1)-f.Base(), start, len(source))
+
+	lines := strings.Split(source, "\n")
+	first, column := lineColumn(source, start)
+	last, _ := lineColumn(source, end)
+	if end > start && source[end-1] == '\n' {
+		// The range ends with the line break
+		last, _ = lineColumn(source, end-1)
+	}
+
+	from, to := first-options.Context, last+options.Context
+	if from < 1 {
+		from = 1
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	color := func(code, text string) string {
+		if !options.Color {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	width := len(fmt.Sprint(to))
+	gutter := func(number string) string {
+		return color(ansiGutter, fmt.Sprintf("%*s |", width, number))
+	}
+
+	var buffer bytes.Buffer
+	name := f.Name()
+	if name == "" {
+		name = "<source>"
+	}
+	fmt.Fprintf(&buffer, "%*s%s %s:%d:%d\n", width, "", color(ansiGutter, "-->"), name, first, column)
+	buffer.WriteString(gutter("") + "\n")
+
+	offset := 0
+	for number := 1; number <= to; number++ {
+		line := lines[number-1]
+		lineStart, lineEnd := offset, offset+len(line)
+		offset = lineEnd + 1
+		if number < from {
+			continue
+		}
+
+		text := strings.TrimRight(line, "\r")
+		if text == "" {
+			buffer.WriteString(gutter(fmt.Sprint(number)) + "\n")
+		} else {
+			buffer.WriteString(gutter(fmt.Sprint(number)) + " " + text + "\n")
+		}
+		if number < first || number > last {
+			continue
+		}
+
+		// The part of the range on this line
+		s, e := clamp(start, lineStart, lineEnd)-lineStart, clamp(end, lineStart, lineEnd)-lineStart
+		if e <= s {
+			if number != first {
+				continue
+			}
+			e = s + 1
+		}
+		underline := "^" + strings.Repeat("~", e-s-1)
+		if number != first {
+			underline = strings.Repeat("~", e-s)
+		}
+		buffer.WriteString(gutter("") + " " + indentation(text, s) + color(ansiCaret, underline) + "\n")
+	}
+
+	return buffer.String()
+}
+
+// lineColumn returns the 1-based line and column of the offset in the source
+func lineColumn(source string, offset int) (line, column int) {
+	before := source[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = offset - strings.LastIndex(before, "\n")
+
+	return line, column
+}
+
+// indentation returns the whitespace aligning text with the given offset of the line, keeping tabs
+func indentation(line string, offset int) string {
+	var buffer bytes.Buffer
+	for i, r := range line {
+		if i >= offset {
+			break
+		}
+		if r == '\t' {
+			buffer.WriteRune('\t')
+		} else {
+			buffer.WriteRune(' ')
+		}
+	}
+	for i := len(line); i < offset; i++ {
+		buffer.WriteRune(' ')
+	}
+
+	return buffer.String()
+}
+
+func clamp(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+
+	return value
+}
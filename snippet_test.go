@@ -0,0 +1,91 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	src := "var a = 1;\nif (a) {\n\tcall(a, b);\n}\n"
+	f := file.NewFile("test.js", src, 1)
+
+	tests := []struct {
+		start, end int
+		options    SnippetOptions
+		expected   string
+	}{
+		{0, 3, SnippetOptions{}, " --> test.js:1:1\n  |\n1 | var a = 1;\n  | ^~~\n"},
+		{21, 31, SnippetOptions{Context: 1}, " --> test.js:3:2\n  |\n2 | if (a) {\n3 | \tcall(a, b);\n  | \t^~~~~~~~~~\n4 | }\n"},
+		{11, 33, SnippetOptions{}, " --> test.js:2:1\n  |\n2 | if (a) {\n  | ^~~~~~~~\n3 | \tcall(a, b);\n  | ~~~~~~~~~~~~\n"},
+		{35, 35, SnippetOptions{Context: 5}, " --> test.js:5:1\n  |\n1 | var a = 1;\n2 | if (a) {\n3 | \tcall(a, b);\n4 | }\n5 |\n  | ^\n"},
+		{4, 5, SnippetOptions{Color: true}, " \x1b[1;34m-->\x1b[0m test.js:1:5\n\x1b[1;34m  |\x1b[0m\n\x1b[1;34m1 |\x1b[0m var a = 1;\n\x1b[1;34m  |\x1b[0m     \x1b[1;31m^\x1b[0m\n"},
+	}
+
+	for i, test := range tests {
+		result := RenderSnippet(f, file.Idx(test.start+1), file.Idx(test.end+1), test.options)
+		if result != test.expected {
+			t.Errorf("[%v] Failed, snippet not correct\n%q\n%q", i, test.expected, result)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	program, err := parser.ParseFile(nil, "", "a();\nfunction f() {\n  return b\n}", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snippet string
+	visitor := &VisitorImpl{}
+	walker := NewWalker(visitor)
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			if identifier, ok := node.(*ast.Identifier); ok && identifier.Name == "b" {
+				snippet = walker.Snippet(node, SnippetOptions{})
+			}
+			return nil
+		},
+	})
+	walker.Begin(program)
+
+	if expected := "3 |   return b\n  |          ^\n"; !strings.HasSuffix(snippet, expected) {
+		t.Errorf("Failed, snippet not correct, %q", snippet)
+	}
+
+	if snippet := NewWalker(visitor).Snippet(program, SnippetOptions{}); snippet != "" {
+		t.Errorf("Failed, expected no snippet before the walk, %q", snippet)
+	}
+}
+
+func TestSnippetEmptyNodes(t *testing.T) {
+	tests := []struct {
+		src      string
+		node     func(program *ast.Program) ast.Node
+		expected string
+	}{
+		{"", func(program *ast.Program) ast.Node {
+			return program
+		}, " --> test.js:1:1\n  |\n1 |\n  | ^\n"},
+		{"switch (a) {\n  case 1:\n  case 2: b()\n}", func(program *ast.Program) ast.Node {
+			return program.Body[0].(*ast.SwitchStatement).Body[0]
+		}, " --> test.js:2:3\n  |\n2 |   case 1:\n  |   ^~~~~~~\n"},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "test.js", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		walker := NewWalker(&VisitorImpl{})
+		walker.Begin(program)
+		if snippet := walker.Snippet(test.node(program), SnippetOptions{}); snippet != test.expected {
+			t.Errorf("[%v] Failed, snippet not correct, %q", i, snippet)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package walker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/robertkrimen/otto/file"
+)
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// SourceMap maps the positions of generated code to the original sources, following the source map revision 3 format
+type SourceMap struct {
+	File string
+	// Sources are the names of the original sources, prefixed with the source root
+	Sources []string
+	// SourcesContent are the original sources, empty if not included in the map
+	SourcesContent []string
+	Names          []string
+
+	// lines holds the segments of each generated line, sorted by column
+	lines [][]segment
+	files map[int]*file.File
+}
+
+// segment maps a 0-based generated column to an original position, source is -1 if the column is not mapped
+type segment struct {
+	column, source, line, originalColumn int
+}
+
+// ParseSourceMap parses the JSON of a source map
+func ParseSourceMap(data []byte) (*SourceMap, error) {
+	var raw struct {
+		Version        int        `json:"version"`
+		File           string     `json:"file"`
+		SourceRoot     string     `json:"sourceRoot"`
+		Sources        []string   `json:"sources"`
+		SourcesContent []*string  `json:"sourcesContent"`
+		Names          []string   `json:"names"`
+		Mappings       string     `json:"mappings"`
+		Sections       []struct{} `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("Unsupported source map version %v", raw.Version)
+	}
+	if raw.Sections != nil {
+		return nil, fmt.Errorf("Indexed source maps are not supported")
+	}
+
+	m := &SourceMap{
+		File:           raw.File,
+		Names:          raw.Names,
+		SourcesContent: make([]string, len(raw.Sources)),
+		files:          map[int]*file.File{},
+	}
+	for i, source := range raw.Sources {
+		if raw.SourceRoot != "" && !path.IsAbs(source) && !strings.Contains(source, "://") {
+			source = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + source
+		}
+		m.Sources = append(m.Sources, source)
+		if i < len(raw.SourcesContent) && raw.SourcesContent[i] != nil {
+			m.SourcesContent[i] = *raw.SourcesContent[i]
+		}
+	}
+
+	if err := m.decode(raw.Mappings); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// decode decodes the Base64 VLQ segments of the mappings
+func (m *SourceMap) decode(mappings string) error {
+	// The fields other than the generated column are relative to the previous segment, across lines
+	var source, line, column int
+	for number, text := range strings.Split(mappings, ";") {
+		var segments []segment
+		generated := 0
+		for _, field := range strings.Split(text, ",") {
+			if field == "" {
+				continue
+			}
+
+			values, err := decodeVLQ(field)
+			if err != nil {
+				return fmt.Errorf("Invalid mapping on generated line %v, %v", number+1, err)
+			}
+			switch len(values) {
+			case 1:
+				generated += values[0]
+				segments = append(segments, segment{column: generated, source: -1})
+			case 4, 5:
+				generated += values[0]
+				source += values[1]
+				line += values[2]
+				column += values[3]
+				if source < 0 || source >= len(m.Sources) {
+					return fmt.Errorf("Invalid source %v on generated line %v", source, number+1)
+				}
+				segments = append(segments, segment{column: generated, source: source, line: line, originalColumn: column})
+			default:
+				return fmt.Errorf("Invalid mapping on generated line %v, segment has %v fields", number+1, len(values))
+			}
+		}
+
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].column < segments[j].column
+		})
+		m.lines = append(m.lines, segments)
+	}
+
+	return nil
+}
+
+// decodeVLQ decodes the Base64 VLQ values of a segment
+func decodeVLQ(field string) ([]int, error) {
+	var values []int
+	value, shift := 0, uint(0)
+	for i := 0; i < len(field); i++ {
+		digit := strings.IndexByte(base64Digits, field[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q", field[i])
+		}
+
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated value")
+	}
+
+	return values, nil
+}
+
+// Original returns the original position of the 1-based generated line and column, nil if it is not mapped.
+// Columns following the start of a segment on the same line are offset from the original column of the segment.
+func (m *SourceMap) Original(line, column int) *file.Position {
+	position, _ := m.original(line, column)
+
+	return position
+}
+
+// original returns the original position and the index of its source, nil and -1 if it is not mapped
+func (m *SourceMap) original(line, column int) (*file.Position, int) {
+	if line < 1 || line > len(m.lines) || column < 1 {
+		return nil, -1
+	}
+
+	segments := m.lines[line-1]
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].column > column-1
+	})
+	if i == 0 || segments[i-1].source < 0 {
+		return nil, -1
+	}
+
+	s := segments[i-1]
+	position := &file.Position{
+		Filename: m.Sources[s.source],
+		Line:     s.line + 1,
+		Column:   s.originalColumn + column - s.column,
+	}
+	if f := m.file(s.source); f != nil {
+		position.Offset = Offset(f, position.Line, position.Column)
+	}
+
+	return position, s.source
+}
+
+// position returns the position of the index in the original source if it is mapped by the source map, else in the file.
+// The generated position is computed from the source of the file, a source map given to the parser is not applied again.
+func position(f *file.File, m *SourceMap, idx file.Idx) *file.Position {
+	if f == nil {
+		return nil
+	}
+
+	position := f.Position(idx)
+	if position == nil || m == nil {
+		return position
+	}
+
+	generated := offsetPosition(f, idx)
+	if original := m.Original(generated.Line, generated.Column); original != nil {
+		return original
+	}
+
+	return &generated
+}
+
+// file returns the original source, nil if the content is not included in the map
+func (m *SourceMap) file(source int) *file.File {
+	if m.SourcesContent[source] == "" {
+		return nil
+	}
+
+	f, ok := m.files[source]
+	if !ok {
+		f = file.NewFile(m.Sources[source], m.SourcesContent[source], 1)
+		m.files[source] = f
+	}
+
+	return f
+}
+
+// snippet renders the original source of the generated range, the empty string if it is not mapped or its content is not known
+func (m *SourceMap) snippet(f *file.File, idx0, idx1 file.Idx, options SnippetOptions) string {
+	start, end := offsetPosition(f, idx0), offsetPosition(f, idx1)
+	original, source := m.original(start.Line, start.Column)
+	if original == nil || m.file(source) == nil || original.Offset < 0 {
+		return ""
+	}
+
+	// The end is mapped if it is in the same source, else the length of the generated range is kept
+	length := end.Offset - start.Offset
+	if last, s := m.original(end.Line, end.Column); last != nil && s == source && last.Offset >= original.Offset {
+		length = last.Offset - original.Offset
+	}
+
+	of := m.file(source)
+	return RenderSnippet(of, file.Idx(original.Offset+of.Base()), file.Idx(original.Offset+length+of.Base()), options)
+}
@@ -0,0 +1,150 @@
+package walker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// The generated source concatenates a.js, a line without mapping and b.js, whose two lines are joined
+const testSourceMap = `{
+	"version": 3,
+	"file": "bundle.js",
+	"sourceRoot": "src",
+	"sources": ["a.js", "b.js"],
+	"sourcesContent": [null, "f();\n  g();\n"],
+	"names": [],
+	"mappings": "AAAA;;ACAA,KACE"
+}`
+
+func TestSourceMap(t *testing.T) {
+	m, err := ParseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line, column int
+		expected     string
+	}{
+		{1, 1, "src/a.js:1:1"},
+		{1, 5, "src/a.js:1:5"},
+		{2, 1, "<nil>"},
+		{3, 1, "src/b.js:1:1"},
+		{3, 6, "src/b.js:2:3"},
+		{3, 8, "src/b.js:2:5"},
+		{4, 1, "<nil>"},
+	}
+
+	for i, test := range tests {
+		if result := fmt.Sprint(m.Original(test.line, test.column)); result != test.expected {
+			t.Errorf("[%v] Failed, position of %v:%v not correct, %v != %v", i, test.line, test.column, test.expected, result)
+		}
+	}
+
+	for i, data := range []string{
+		`{"version": 2, "sources": [], "mappings": ""}`,
+		`{"version": 3, "sources": [], "mappings": "A!"}`,
+		`{"version": 3, "sources": [], "mappings": "AAAA"}`,
+		`{"version": 3, "sources": ["a.js"], "mappings": "AA"}`,
+		`{"version": 3, "sources": ["a.js"], "mappings": "AAAg"}`,
+	} {
+		if _, err := ParseSourceMap([]byte(data)); err == nil {
+			t.Errorf("[%v] Failed, expected an error", i)
+		}
+	}
+}
+
+func TestWalkerSourceMap(t *testing.T) {
+	m, err := ParseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := parser.ParseFile(nil, "bundle.js", "var a = 1;\n// generated\nf(); g();", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var diagnostics []*Diagnostic
+	var snippets []string
+	visitor := &VisitorImpl{}
+	walker := NewWalker(visitor)
+	walker.SourceMap = m
+	visitor.AddHook(&Hook{
+		OnNode: func(node ast.Node, metadata []Metadata) error {
+			switch n := node.(type) {
+			case *ast.CallExpression:
+				diagnostics = append(diagnostics, NewDiagnostic(n, Warning, "call", "call"))
+				snippets = append(snippets, walker.Snippet(n, SnippetOptions{}))
+			case *ast.VariableExpression:
+				snippets = append(snippets, walker.Snippet(n, SnippetOptions{}))
+			}
+			return nil
+		},
+	})
+	walker.Begin(program)
+	walker.Locate(diagnostics...)
+
+	var positions []string
+	for _, d := range diagnostics {
+		positions = append(positions, strings.SplitN(d.String(), ": ", 2)[0])
+	}
+	if strings.Join(positions, ", ") != "src/b.js:1:1, src/b.js:2:3" {
+		t.Errorf("Failed, positions not correct, %v", positions)
+	}
+
+	expected := []string{
+		// a.js is not included, the generated source is rendered
+		" --> bundle.js:1:5\n  |\n1 | var a = 1;\n  |     ^~~~~\n",
+		" --> src/b.js:1:1\n  |\n1 | f();\n  | ^~~\n",
+		" --> src/b.js:2:3\n  |\n2 |   g();\n  |   ^~~\n",
+	}
+	if strings.Join(snippets, "") != strings.Join(expected, "") {
+		t.Errorf("Failed, snippets not correct\n%q", snippets)
+	}
+
+	// Positions of lines not mapped are reported in the generated source
+	if position := walker.GetPosition(file.Idx(program.File.Base() + 11)); position == nil || position.String() != "bundle.js:2:1" {
+		t.Errorf("Failed, fallback position not correct, %v", position)
+	}
+}
+
+func TestAnalyzerSourceMap(t *testing.T) {
+	m, err := ParseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := "var a = 1;\n// generated\nf(); l: g();"
+
+	parse := func(mapped bool) *ast.Program {
+		var program *ast.Program
+		var err error
+		if mapped {
+			program, err = parser.ParseFileWithSourceMap(nil, "bundle.js", src, testSourceMap, 0)
+		} else {
+			program, err = parser.ParseFile(nil, "bundle.js", src, 0)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return program
+	}
+
+	// A source map given to the parser is replaced by the one of the walker
+	for i, program := range []*ast.Program{parse(false), parse(true)} {
+		analyzer := NewLabelAnalyzer()
+		visitor := &VisitorImpl{}
+		visitor.AddHook(analyzer.Hook())
+		walker := NewWalker(visitor)
+		walker.SourceMap = m
+		walker.Begin(program)
+
+		if len(analyzer.Diagnostics) != 1 || fmt.Sprint(analyzer.Diagnostics[0].Position) != "src/b.js:2:3" {
+			t.Errorf("[%v] Failed, diagnostics not located, %v", i, analyzer.Diagnostics)
+		}
+	}
+}
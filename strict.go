@@ -0,0 +1,106 @@
+package walker
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+// StrictModeCode is the code of diagnostics reporting constructs illegal in strict mode
+const StrictModeCode = "strict-mode"
+
+var (
+	octalNumberPattern = regexp.MustCompile(`^0[0-9]+$`)
+	octalEscapePattern = regexp.MustCompile(`(^|[^\\])(\\\\)*\\(0[0-9]|[1-7])`)
+)
+
+// HasUseStrict returns true if the directive prologue of the statements contains "use strict"
+func HasUseStrict(statements []ast.Statement) bool {
+	for _, statement := range statements {
+		expression, ok := statement.(*ast.ExpressionStatement)
+		if !ok {
+			return false
+		}
+		literal, ok := expression.Expression.(*ast.StringLiteral)
+		if !ok {
+			return false
+		}
+
+		// The directive must not contain escapes or line continuations
+		if len(literal.Literal) > 2 && literal.Literal[1:len(literal.Literal)-1] == "use strict" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsStrict returns true if the current node is in strict code
+func IsStrict(metadata []Metadata) bool {
+	for i := len(metadata) - 1; i >= 0; i-- {
+		if strict, ok := metadata[i][Strict].(bool); ok {
+			return strict
+		}
+	}
+
+	return false
+}
+
+// StrictModeAnalyzer reports constructs illegal in strict code:
+// with statements, octal literals, deletion of variables and duplicate parameter names.
+type StrictModeAnalyzer struct {
+	Diagnostics []*Diagnostic
+}
+
+// NewStrictModeAnalyzer returns a new instance
+func NewStrictModeAnalyzer() *StrictModeAnalyzer {
+	return &StrictModeAnalyzer{}
+}
+
+// Hook returns the hook performing the analysis
+func (a *StrictModeAnalyzer) Hook() *Hook {
+	return &Hook{
+		OnNode: a.onNode,
+	}
+}
+
+func (a *StrictModeAnalyzer) onNode(node ast.Node, metadata []Metadata) error {
+	if !IsStrict(metadata) {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *ast.WithStatement:
+		a.report(metadata, n, "with statement is not allowed in strict mode")
+	case *ast.NumberLiteral:
+		if octalNumberPattern.MatchString(n.Literal) {
+			a.report(metadata, n, fmt.Sprintf("octal literal %v is not allowed in strict mode", n.Literal))
+		}
+	case *ast.StringLiteral:
+		if octalEscapePattern.MatchString(n.Literal) {
+			a.report(metadata, n, "octal escape sequence is not allowed in strict mode")
+		}
+	case *ast.UnaryExpression:
+		if identifier, ok := n.Operand.(*ast.Identifier); ok && n.Operator == token.DELETE {
+			a.report(metadata, n, fmt.Sprintf("delete of variable %v is not allowed in strict mode", identifier.Name))
+		}
+	case *ast.FunctionLiteral:
+		seen := map[string]bool{}
+		for _, parameter := range n.ParameterList.List {
+			if seen[parameter.Name] {
+				a.report(metadata, parameter, fmt.Sprintf("duplicate parameter %v is not allowed in strict mode", parameter.Name))
+			}
+			seen[parameter.Name] = true
+		}
+	}
+
+	return nil
+}
+
+func (a *StrictModeAnalyzer) report(metadata []Metadata, node ast.Node, message string) {
+	diagnostic := NewDiagnostic(node, Error, StrictModeCode, message)
+	diagnostic.Locate(CurrentMetadata(metadata))
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
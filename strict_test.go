@@ -0,0 +1,60 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+)
+
+type strictVisitor struct {
+	VisitorImpl
+	strict map[string]bool
+}
+
+func (v *strictVisitor) VisitIdentifier(w *Walker, node *ast.Identifier, metadata []Metadata) Metadata {
+	v.strict[node.Name] = IsStrict(metadata)
+
+	return CurrentMetadata(metadata)
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		src         string
+		strict      map[string]bool
+		diagnostics int
+	}{
+		{`a; function f() { b }`, map[string]bool{"a": false, "b": false}, 0},
+		{`"use strict"; a; function f() { b }`, map[string]bool{"a": true, "b": true}, 0},
+		{`a; function f() { 'use strict'; b; function g() { c } }`, map[string]bool{"a": false, "b": true, "c": true}, 0},
+		{`a; function f() { "use\x20strict"; b }`, map[string]bool{"a": false, "b": false}, 0},
+		{`a; "use strict"; function f() { b }`, map[string]bool{"a": false, "b": false}, 0},
+		{`"foo"; "use strict"; with (a) {}`, map[string]bool{"a": true}, 1},
+		{`"use strict"; a = 010 + 0.5 + 0 + "\01" + "\\01" + "\0"`, map[string]bool{"a": true}, 2},
+		{`"use strict"; delete a; delete a.b`, map[string]bool{"a": true}, 1},
+		{`function f(a, a) { "use strict" }`, map[string]bool{"a": true}, 1},
+		{`with (a) { delete a; (function(b, b) {}) }`, map[string]bool{"a": false}, 0},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		analyzer := NewStrictModeAnalyzer()
+		visitor := &strictVisitor{strict: map[string]bool{}}
+		visitor.AddHook(analyzer.Hook())
+		NewWalker(visitor).Begin(program)
+
+		for name, strict := range test.strict {
+			if visitor.strict[name] != strict {
+				t.Errorf("[%v] Failed, strictness of %v not correct, %v != %v", i, name, strict, visitor.strict[name])
+			}
+		}
+		if test.diagnostics != len(analyzer.Diagnostics) {
+			t.Errorf("[%v] Failed, number of diagnostics not correct, %v != %v", i, test.diagnostics, analyzer.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+// Package taint reports flows of untrusted values from sources to sinks in programs walked by the walker.
+package taint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+	"github.com/wolfgarnet/walker/callgraph"
+)
+
+// TaintedFlowCode is the code of diagnostics reporting untrusted values reaching sinks
+const TaintedFlowCode = "tainted-flow"
+
+// Sink is a function receiving values that must be trusted
+type Sink struct {
+	// Pattern matches the callee, e.g. "db.query"
+	Pattern string
+	// Arguments lists the positions of the checked arguments, all if empty
+	Arguments []int
+}
+
+// Config declares the sources, sinks and sanitizers.
+// Patterns are member expressions, where * matches any name, e.g. "request.*.name".
+type Config struct {
+	// Sources are untrusted values, including their properties
+	Sources []string
+	// Handlers match callees receiving functions whose parameters are untrusted, e.g. "app.post"
+	Handlers []string
+	Sinks    []Sink
+	// Sanitizers are functions returning trusted values
+	Sanitizers []string
+}
+
+// DefaultConfig returns a configuration with common sources and the sinks evaluating code
+func DefaultConfig() *Config {
+	return &Config{
+		Sources: []string{"request.body", "request.query", "request.params", "request.headers"},
+		Sinks: []Sink{
+			{Pattern: "eval"},
+			{Pattern: "Function"},
+			{Pattern: "setTimeout", Arguments: []int{0}},
+			{Pattern: "setInterval", Arguments: []int{0}},
+		},
+	}
+}
+
+// Finding is a flow of an untrusted value into a sink
+type Finding struct {
+	// Source is the expression or parameter the value originates from
+	Source ast.Node
+	// Call is the *ast.CallExpression or *ast.NewExpression of the sink
+	Call     ast.Expression
+	Argument ast.Expression
+	Sink     Sink
+}
+
+// assignment of a value to a variable, or to a property of it if the target is a member expression
+type assignment struct {
+	binding walker.Binding
+	target  ast.Expression
+	value   ast.Expression
+}
+
+// member is a property of a variable, e.g. "c.d" of o.c.d
+type member struct {
+	binding walker.Binding
+	path    string
+}
+
+// Analyzer finds flows through assignments, operators and calls, regardless of the order they are executed in.
+// Calls of functions of the program are followed into their parameters and out of their return values,
+// other calls return untrusted values if any argument or the object called is untrusted.
+// Properties assigned to variables are tracked by name, e.g. o.c after o.c = request.body.
+type Analyzer struct {
+	Config      *Config
+	Findings    []*Finding
+	Diagnostics []*walker.Diagnostic
+
+	program     *ast.Program
+	reads       map[*ast.Identifier]walker.Binding
+	assignments []assignment
+	calls       []ast.Expression
+	returns     map[*ast.FunctionLiteral][]ast.Expression
+	callees     map[ast.Expression][]*ast.FunctionLiteral
+
+	tainted        map[walker.Binding]ast.Node
+	taintedMembers map[member]ast.Node
+	taintedReturns map[*ast.FunctionLiteral]ast.Node
+}
+
+// NewAnalyzer returns a new instance
+func NewAnalyzer(config *Config) *Analyzer {
+	return &Analyzer{
+		Config:         config,
+		reads:          map[*ast.Identifier]walker.Binding{},
+		returns:        map[*ast.FunctionLiteral][]ast.Expression{},
+		callees:        map[ast.Expression][]*ast.FunctionLiteral{},
+		tainted:        map[walker.Binding]ast.Node{},
+		taintedMembers: map[member]ast.Node{},
+		taintedReturns: map[*ast.FunctionLiteral]ast.Node{},
+	}
+}
+
+// Hook returns the hook collecting the flows, they are analyzed when the walk of the program is finished
+func (a *Analyzer) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode:     a.onNode,
+		OnFinished: a.onFinished,
+	}
+}
+
+func (a *Analyzer) onNode(node ast.Node, metadata []walker.Metadata) error {
+	switch n := node.(type) {
+	case *ast.Program:
+		a.program = n
+	case *ast.VariableExpression:
+		binding := walker.ResolveBinding(metadata, n.Name)
+		if forIn, ok := walker.ParentMetadata(metadata).Node().(*ast.ForInStatement); ok && forIn.Into == n {
+			a.assignments = append(a.assignments, assignment{binding, nil, forIn.Source})
+		} else if n.Initializer != nil {
+			a.assignments = append(a.assignments, assignment{binding, nil, n.Initializer})
+		}
+	case *ast.AssignExpression:
+		// The variable of a member expression is resolved when the walk is finished
+		switch n.Left.(type) {
+		case *ast.DotExpression, *ast.BracketExpression:
+			a.assignments = append(a.assignments, assignment{target: n.Left, value: n})
+		}
+	case *ast.Identifier:
+		access := walker.IdentifierAccess(metadata)
+		if access == 0 {
+			return nil
+		}
+
+		binding := walker.ResolveBinding(metadata, n.Name)
+		if access&walker.Read != 0 {
+			a.reads[n] = binding
+		}
+		switch p := walker.ParentMetadata(metadata).Node().(type) {
+		case *ast.AssignExpression:
+			if p.Left == n {
+				a.assignments = append(a.assignments, assignment{binding, nil, p})
+			}
+		case *ast.ForInStatement:
+			a.assignments = append(a.assignments, assignment{binding, nil, p.Source})
+		}
+	case *ast.CallExpression, *ast.NewExpression:
+		a.calls = append(a.calls, n.(ast.Expression))
+	case *ast.ReturnStatement:
+		if function := walker.FindParentFunction(metadata); function != nil && n.Argument != nil {
+			a.returns[function] = append(a.returns[function], n.Argument)
+		}
+	}
+
+	return nil
+}
+
+func (a *Analyzer) onFinished(node ast.Node, metadata walker.Metadata) error {
+	if a.program == nil {
+		return nil
+	}
+
+	for _, edge := range callgraph.Build(a.program).Edges {
+		if edge.Callee.Function != nil {
+			a.callees[edge.Site] = append(a.callees[edge.Site], edge.Callee.Function)
+		}
+	}
+
+	a.taintHandlers()
+	a.solve()
+	a.check(metadata)
+
+	return nil
+}
+
+// taintHandlers marks the parameters of functions given to handlers as untrusted
+func (a *Analyzer) taintHandlers() {
+	for _, call := range a.calls {
+		callee, arguments := parts(call)
+		if !a.matchesAny(a.Config.Handlers, callee, false) {
+			continue
+		}
+
+		for _, argument := range arguments {
+			function, ok := argument.(*ast.FunctionLiteral)
+			if !ok {
+				continue
+			}
+			for _, parameter := range function.ParameterList.List {
+				a.tainted[walker.Binding{Scope: function, Name: parameter.Name}] = parameter
+			}
+		}
+	}
+}
+
+// solve propagates the untrusted values until nothing changes
+func (a *Analyzer) solve() {
+	for changed := true; changed; {
+		changed = false
+		taint := func(binding walker.Binding, source ast.Node) {
+			if _, ok := a.tainted[binding]; !ok && source != nil {
+				a.tainted[binding] = source
+				changed = true
+			}
+		}
+
+		for _, assignment := range a.assignments {
+			if assignment.target == nil {
+				taint(assignment.binding, a.Source(assignment.value))
+				continue
+			}
+
+			m, ok := a.member(assignment.target)
+			if !ok {
+				// Properties computed at runtime may be any property of the variable
+				if binding, ok := a.variable(assignment.target); ok {
+					taint(binding, a.Source(assignment.value))
+				}
+				continue
+			}
+			if _, ok := a.taintedMembers[m]; !ok {
+				if source := a.Source(assignment.value); source != nil {
+					a.taintedMembers[m] = source
+					changed = true
+				}
+			}
+		}
+
+		for _, call := range a.calls {
+			_, arguments := parts(call)
+			for _, function := range a.callees[call] {
+				for i, parameter := range function.ParameterList.List {
+					if i < len(arguments) {
+						taint(walker.Binding{Scope: function, Name: parameter.Name}, a.Source(arguments[i]))
+					}
+				}
+			}
+		}
+
+		for function, arguments := range a.returns {
+			if _, ok := a.taintedReturns[function]; ok {
+				continue
+			}
+			for _, argument := range arguments {
+				if source := a.Source(argument); source != nil {
+					a.taintedReturns[function] = source
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// Source returns the origin of the untrusted value of the expression, nil if it is trusted
+func (a *Analyzer) Source(expression ast.Expression) ast.Node {
+	if a.matchesAny(a.Config.Sources, expression, true) {
+		return expression
+	}
+
+	switch n := expression.(type) {
+	case *ast.Identifier:
+		if binding, ok := a.reads[n]; ok {
+			return a.tainted[binding]
+		}
+	case *ast.DotExpression:
+		if source := a.memberSource(n); source != nil {
+			return source
+		}
+		return a.Source(n.Left)
+	case *ast.BracketExpression:
+		if source := a.memberSource(n); source != nil {
+			return source
+		}
+		return a.Source(n.Left)
+	case *ast.BinaryExpression:
+		return a.first(n.Left, n.Right)
+	case *ast.AssignExpression:
+		if n.Operator == token.ASSIGN {
+			return a.Source(n.Right)
+		}
+		return a.first(n.Left, n.Right)
+	case *ast.ConditionalExpression:
+		return a.first(n.Consequent, n.Alternate)
+	case *ast.SequenceExpression:
+		if len(n.Sequence) > 0 {
+			return a.Source(n.Sequence[len(n.Sequence)-1])
+		}
+	case *ast.UnaryExpression:
+		switch n.Operator {
+		case token.TYPEOF, token.VOID, token.DELETE, token.NOT:
+			return nil
+		}
+		return a.Source(n.Operand)
+	case *ast.ArrayLiteral:
+		return a.first(n.Value...)
+	case *ast.ObjectLiteral:
+		for _, property := range n.Value {
+			if source := a.Source(property.Value); source != nil {
+				return source
+			}
+		}
+	case *ast.CallExpression, *ast.NewExpression:
+		callee, arguments := parts(n)
+		if a.matchesAny(a.Config.Sanitizers, callee, false) {
+			return nil
+		}
+		if functions, ok := a.callees[n]; ok {
+			for _, function := range functions {
+				if source := a.taintedReturns[function]; source != nil {
+					return source
+				}
+			}
+			return nil
+		}
+		if dot, ok := callee.(*ast.DotExpression); ok {
+			if source := a.Source(dot.Left); source != nil {
+				return source
+			}
+		}
+		return a.first(arguments...)
+	}
+
+	return nil
+}
+
+// member returns the property of a variable the member expression refers to
+func (a *Analyzer) member(expression ast.Expression) (member, bool) {
+	names := path(expression)
+	if len(names) < 2 {
+		return member{}, false
+	}
+
+	binding, ok := a.variable(expression)
+	if !ok {
+		return member{}, false
+	}
+
+	return member{binding, strings.Join(names[1:], ".")}, true
+}
+
+// variable returns the binding of the variable the member expression is a property of
+func (a *Analyzer) variable(expression ast.Expression) (walker.Binding, bool) {
+	for {
+		switch e := expression.(type) {
+		case *ast.DotExpression:
+			expression = e.Left
+			continue
+		case *ast.BracketExpression:
+			expression = e.Left
+			continue
+		}
+		break
+	}
+
+	identifier, ok := expression.(*ast.Identifier)
+	if !ok {
+		return walker.Binding{}, false
+	}
+	binding, ok := a.reads[identifier]
+
+	return binding, ok
+}
+
+// memberSource returns the origin of the untrusted value assigned to the property or to one containing it, nil if none
+func (a *Analyzer) memberSource(expression ast.Expression) ast.Node {
+	m, ok := a.member(expression)
+	if !ok {
+		return nil
+	}
+
+	for tainted, source := range a.taintedMembers {
+		if tainted.binding == m.binding && (tainted.path == m.path || strings.HasPrefix(m.path, tainted.path+".")) {
+			return source
+		}
+	}
+
+	return nil
+}
+
+// first returns the origin of the first untrusted expression, nil if none
+func (a *Analyzer) first(expressions ...ast.Expression) ast.Node {
+	for _, expression := range expressions {
+		if expression == nil {
+			continue
+		}
+		if source := a.Source(expression); source != nil {
+			return source
+		}
+	}
+
+	return nil
+}
+
+// check reports the untrusted arguments of sinks
+func (a *Analyzer) check(md walker.Metadata) {
+	for _, call := range a.calls {
+		callee, arguments := parts(call)
+		for _, sink := range a.Config.Sinks {
+			if !matches(sink.Pattern, path(callee), false) {
+				continue
+			}
+
+			for i, argument := range arguments {
+				if !sink.checks(i) {
+					continue
+				}
+				if source := a.Source(argument); source != nil {
+					a.report(md, &Finding{Source: source, Call: call, Argument: argument, Sink: sink})
+				}
+			}
+		}
+	}
+}
+
+// checks returns true if the argument at the position is checked
+func (s Sink) checks(i int) bool {
+	if len(s.Arguments) == 0 {
+		return true
+	}
+	for _, argument := range s.Arguments {
+		if argument == i {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Analyzer) report(md walker.Metadata, finding *Finding) {
+	a.Findings = append(a.Findings, finding)
+	message := fmt.Sprintf("untrusted value from %v reaches %v", a.text(finding.Source), finding.Sink.Pattern)
+	diagnostic := walker.NewDiagnostic(finding.Call, walker.Warning, TaintedFlowCode, message)
+	diagnostic.Locate(md)
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
+
+// text returns the source of the node
+func (a *Analyzer) text(node ast.Node) string {
+	if a.program == nil || a.program.File == nil {
+		return fmt.Sprintf("%T", node)
+	}
+
+	md := walker.NewMetadata(node)
+	md[walker.FileField] = a.program.File
+	r := md.Range()
+	return a.program.File.Source()[r.Start.Offset:r.End.Offset]
+}
+
+// parts returns the callee and arguments of a call or new expression
+func parts(call ast.Expression) (ast.Expression, []ast.Expression) {
+	switch c := call.(type) {
+	case *ast.CallExpression:
+		return c.Callee, c.ArgumentList
+	case *ast.NewExpression:
+		return c.Callee, c.ArgumentList
+	}
+
+	return nil, nil
+}
+
+// path returns the names of a member expression, nil if it is not made of names
+func path(expression ast.Expression) []string {
+	switch e := expression.(type) {
+	case *ast.Identifier:
+		return []string{e.Name}
+	case *ast.ThisExpression:
+		return []string{"this"}
+	case *ast.DotExpression:
+		if left := path(e.Left); left != nil {
+			return append(left, e.Identifier.Name)
+		}
+	case *ast.BracketExpression:
+		if member, ok := e.Member.(*ast.StringLiteral); ok {
+			if left := path(e.Left); left != nil {
+				return append(left, member.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if the pattern matches the path, or a prefix of it if prefix is true
+func matches(pattern string, path []string, prefix bool) bool {
+	segments := strings.Split(pattern, ".")
+	if len(path) < len(segments) || !prefix && len(path) != len(segments) {
+		return false
+	}
+
+	for i, segment := range segments {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesAny returns true if any of the patterns matches the expression
+func (a *Analyzer) matchesAny(patterns []string, expression ast.Expression, prefix bool) bool {
+	p := path(expression)
+	if p == nil {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if matches(pattern, p, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
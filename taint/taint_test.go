@@ -0,0 +1,77 @@
+package taint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+)
+
+func TestAnalyzer(t *testing.T) {
+	config := DefaultConfig()
+	config.Handlers = []string{"app.post"}
+	config.Sinks = append(config.Sinks, Sink{Pattern: "db.query", Arguments: []int{0}})
+	config.Sanitizers = []string{"escape", "*.escape"}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`eval(request.body)`, "request.body reaches eval"},
+		{`eval(request.body.code)`, "request.body.code reaches eval"},
+		{`var code = "x" + request.query.q; eval(code)`, "request.query.q reaches eval"},
+		{`var a = request.body, b; b = a; new Function(b)`, "request.body reaches Function"},
+		{`setTimeout(request.body, 10)`, "request.body reaches setTimeout"},
+		{`setTimeout(function() {}, request.body)`, ""},
+		{`db.query("select " + request.params.id)`, "request.params.id reaches db.query"},
+		{`db.query("select ?", [request.params.id])`, ""},
+		{`eval(escape(request.body))`, ""},
+		{`eval(db.escape(request.body))`, ""},
+		{`eval(request.body.trim())`, "request.body reaches eval"},
+		{`eval(JSON.stringify(request.body))`, "request.body reaches eval"},
+		{`function id(x) { return x } eval(id(request.body))`, "request.body reaches eval"},
+		{`function f(x) { return "1" } eval(f(request.body))`, ""},
+		{`function run(s) { eval(s) } run(request.headers.h)`, "request.headers.h reaches eval"},
+		{`app.post("/", function(req, res) { eval(req.data) })`, "req reaches eval"},
+		{`app.get("/", function(req, res) { eval(req.data) })`, ""},
+		{`for (var k in request.body) { eval(k) }`, "request.body reaches eval"},
+		{`var x = request.body; x = "safe"; eval(typeof x)`, ""},
+		{`eval("1 + 1")`, ""},
+		{`x = request.body; setTimeout(x)`, "request.body reaches setTimeout"},
+		{`function f() { x = request.body } function g() { setTimeout(x) }`, "request.body reaches setTimeout"},
+		{`var o = {}; o.c = request.body; setTimeout(o.c)`, "request.body reaches setTimeout"},
+		{`var o = {}; o["c"] = request.body; eval(o.c.d)`, "request.body reaches eval"},
+		{`var o = {}; o.c = request.body; eval(o.d)`, ""},
+		{`var o = {}, p = {}; o.c = request.body; eval(p.c)`, ""},
+		{`var o = {}; o.c = request.body; function f(o) { eval(o.c) }`, ""},
+		{`var o = {}; o[k] = request.body; eval(o.x)`, "request.body reaches eval"},
+		{`var o = {}; o.c[k] = request.body; setTimeout(o)`, "request.body reaches setTimeout"},
+		{`var o = {}; o[k] = 1; eval(o.x)`, ""},
+		{`eval(request["body"])`, `request["body"] reaches eval`},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		analyzer := NewAnalyzer(config)
+		visitor := &walker.VisitorImpl{}
+		visitor.AddHook(analyzer.Hook())
+		walker.NewWalker(visitor).Begin(program)
+
+		var result []string
+		for _, d := range analyzer.Diagnostics {
+			result = append(result, strings.TrimPrefix(d.Message, "untrusted value from "))
+			if d.Position == nil || d.Position.Offset != int(d.Node.Idx0())-program.File.Base() {
+				t.Errorf("[%v] Failed, diagnostic not located, %v", i, d.Position)
+			}
+		}
+		if strings.Join(result, ", ") != test.expected {
+			t.Errorf("[%v] Failed, %v: %q != %q", i, test.src, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+	"github.com/wolfgarnet/walker"
+)
+
+const (
+	// PropertyOnPrimitiveCode is the code of diagnostics reporting properties read from numbers, booleans or undefined
+	PropertyOnPrimitiveCode = "property-on-primitive"
+	// StringArithmeticCode is the code of diagnostics reporting arithmetic on strings
+	StringArithmeticCode = "string-arithmetic"
+)
+
+// maxIterations bounds the fixed point computation of the variable types
+const maxIterations = 100
+
+// primitiveProperties are the properties of numbers and booleans, from their prototypes
+var primitiveProperties = map[string]bool{
+	"toFixed": true, "toExponential": true, "toPrecision": true, "toString": true, "toLocaleString": true,
+	"valueOf": true, "constructor": true, "hasOwnProperty": true, "isPrototypeOf": true, "propertyIsEnumerable": true,
+}
+
+// assignment of a value to a variable
+type assignment struct {
+	value ast.Expression
+	// operator of compound assignments, the variable is then read too
+	operator token.Token
+	target   *ast.Identifier
+	// fixed is the type of assignments without a value expression, e.g. parameters
+	fixed Type
+}
+
+// Inferrer infers the types of variables from all their assignments, regardless of the order they are executed in.
+// The types are available after the walk.
+type Inferrer struct {
+	Variables   map[walker.Binding]Type
+	Diagnostics []*walker.Diagnostic
+
+	reads       map[*ast.Identifier]walker.Binding
+	assignments map[walker.Binding][]assignment
+	checks      []ast.Expression
+}
+
+// NewInferrer returns a new instance
+func NewInferrer() *Inferrer {
+	return &Inferrer{
+		Variables:   map[walker.Binding]Type{},
+		reads:       map[*ast.Identifier]walker.Binding{},
+		assignments: map[walker.Binding][]assignment{},
+	}
+}
+
+// Hook returns the hook collecting the assignments, the types are inferred when the walk is finished
+func (in *Inferrer) Hook() *walker.Hook {
+	return &walker.Hook{
+		OnNode:     in.onNode,
+		OnFinished: in.onFinished,
+	}
+}
+
+func (in *Inferrer) onNode(node ast.Node, metadata []walker.Metadata) error {
+	parent := walker.ParentMetadata(metadata).Node()
+	switch n := node.(type) {
+	case *ast.VariableExpression:
+		binding := in.declare(metadata, n.Name)
+		if forIn, ok := parent.(*ast.ForInStatement); ok && forIn.Into == n {
+			in.assign(binding, assignment{fixed: String})
+		} else if n.Initializer != nil {
+			in.assign(binding, assignment{value: n.Initializer, operator: token.ASSIGN})
+		}
+	case *ast.Identifier:
+		access := walker.IdentifierAccess(metadata)
+		if access == 0 {
+			switch p := parent.(type) {
+			case *ast.FunctionLiteral:
+				if p.Name == n {
+					in.assign(in.declare(metadata, n.Name), assignment{fixed: Function})
+				} else {
+					in.assign(in.declare(metadata, n.Name), assignment{fixed: Unknown})
+				}
+			case *ast.CatchStatement:
+				in.assign(in.declare(metadata, n.Name), assignment{fixed: Unknown})
+			}
+			return nil
+		}
+
+		binding := walker.ResolveBinding(metadata, n.Name)
+		if access&walker.Read != 0 {
+			in.reads[n] = binding
+		}
+		if access&walker.Write != 0 {
+			switch p := parent.(type) {
+			case *ast.AssignExpression:
+				in.assign(binding, assignment{value: p.Right, operator: p.Operator, target: n})
+			case *ast.UnaryExpression:
+				in.assign(binding, assignment{fixed: Number})
+			case *ast.ForInStatement:
+				in.assign(binding, assignment{fixed: String})
+			default:
+				in.assign(binding, assignment{fixed: Unknown})
+			}
+		}
+	case *ast.DotExpression, *ast.BinaryExpression, *ast.UnaryExpression, *ast.AssignExpression:
+		in.checks = append(in.checks, n.(ast.Expression))
+	}
+
+	return nil
+}
+
+// declare returns the binding of the declared name.
+// Variables of scopes calling eval can be assigned anything.
+func (in *Inferrer) declare(metadata []walker.Metadata, name string) walker.Binding {
+	binding := walker.ResolveBinding(metadata, name)
+	if _, ok := in.Variables[binding]; !ok {
+		in.Variables[binding] = None
+	}
+	for _, md := range metadata {
+		if dynamic, _ := md[walker.Dynamic].(bool); dynamic && md.Node() == binding.Scope {
+			in.assign(binding, assignment{fixed: Unknown})
+		}
+	}
+
+	return binding
+}
+
+func (in *Inferrer) assign(binding walker.Binding, a assignment) {
+	in.assignments[binding] = append(in.assignments[binding], a)
+}
+
+func (in *Inferrer) onFinished(node ast.Node, metadata walker.Metadata) error {
+	in.Solve()
+	in.check(metadata)
+
+	return nil
+}
+
+// Solve computes the types of the variables from their assignments
+func (in *Inferrer) Solve() {
+	for i := 0; i < maxIterations; i++ {
+		changed := false
+		for binding, assignments := range in.assignments {
+			t := in.Variables[binding]
+			for _, a := range assignments {
+				t |= in.assignmentType(a)
+			}
+			if t != in.Variables[binding] {
+				in.Variables[binding] = t
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Declared variables never assigned are undefined
+	for binding, t := range in.Variables {
+		if t == None && binding.Scope != nil {
+			in.Variables[binding] = Undefined
+		}
+	}
+}
+
+func (in *Inferrer) assignmentType(a assignment) Type {
+	switch {
+	case a.value == nil:
+		return a.fixed
+	case a.operator == token.ASSIGN:
+		return in.Type(a.value)
+	}
+
+	return binaryType(a.operator, in.Type(a.target), in.Type(a.value))
+}
+
+// Type returns the inferred type of the expression
+func (in *Inferrer) Type(expression ast.Expression) Type {
+	switch n := expression.(type) {
+	case *ast.NumberLiteral:
+		return Number
+	case *ast.StringLiteral:
+		return String
+	case *ast.BooleanLiteral:
+		return Boolean
+	case *ast.NullLiteral, *ast.RegExpLiteral, *ast.ObjectLiteral, *ast.NewExpression:
+		return Object
+	case *ast.ArrayLiteral:
+		return Array
+	case *ast.FunctionLiteral:
+		return Function
+	case *ast.Identifier:
+		binding, ok := in.reads[n]
+		switch {
+		case !ok || binding.Unknown:
+			return Unknown
+		case binding.Scope == nil && n.Name == "undefined":
+			return Undefined
+		case binding.Scope == nil && (n.Name == "NaN" || n.Name == "Infinity"):
+			return Number
+		case binding.Scope == nil:
+			return Unknown
+		}
+		return in.Variables[binding]
+	case *ast.UnaryExpression:
+		switch n.Operator {
+		case token.TYPEOF:
+			return String
+		case token.NOT, token.DELETE:
+			return Boolean
+		case token.VOID:
+			return Undefined
+		}
+		return Number
+	case *ast.BinaryExpression:
+		return binaryType(n.Operator, in.Type(n.Left), in.Type(n.Right))
+	case *ast.AssignExpression:
+		if n.Operator == token.ASSIGN {
+			return in.Type(n.Right)
+		}
+		return binaryType(n.Operator, in.Type(n.Left), in.Type(n.Right))
+	case *ast.ConditionalExpression:
+		return in.Type(n.Consequent) | in.Type(n.Alternate)
+	case *ast.SequenceExpression:
+		if len(n.Sequence) > 0 {
+			return in.Type(n.Sequence[len(n.Sequence)-1])
+		}
+	}
+
+	return Unknown
+}
+
+// binaryType returns the type of the result of the operator
+func binaryType(operator token.Token, left, right Type) Type {
+	switch operator {
+	case token.PLUS:
+		switch {
+		case left.Is(String) || right.Is(String):
+			return String
+		case left == None || right == None:
+			// Not inferred yet
+			return None
+		case (left|right)&Unknown != 0:
+			return Unknown
+		case (left | right).Is(Number | Boolean | Undefined):
+			return Number
+		}
+		return Number | String
+	case token.LOGICAL_AND, token.LOGICAL_OR:
+		return left | right
+	case token.LESS, token.GREATER, token.LESS_OR_EQUAL, token.GREATER_OR_EQUAL, token.EQUAL, token.NOT_EQUAL,
+		token.STRICT_EQUAL, token.STRICT_NOT_EQUAL, token.IN, token.INSTANCEOF:
+		return Boolean
+	}
+
+	return Number
+}
+
+// isArithmetic returns true if the operator converts its operands to numbers
+func isArithmetic(operator token.Token) bool {
+	switch operator {
+	case token.MINUS, token.MULTIPLY, token.SLASH, token.REMAINDER:
+		return true
+	}
+
+	return false
+}
+
+// check reports suspicious uses of the inferred types
+func (in *Inferrer) check(md walker.Metadata) {
+	for _, expression := range in.checks {
+		switch n := expression.(type) {
+		case *ast.DotExpression:
+			t := in.Type(n.Left)
+			name := n.Identifier.Name
+			if t.Is(Undefined) {
+				in.report(md, n, PropertyOnPrimitiveCode, fmt.Sprintf("property %v is read from undefined", name))
+			} else if t.Is(Number|Boolean|Undefined) && !primitiveProperties[name] {
+				in.report(md, n, PropertyOnPrimitiveCode, fmt.Sprintf("property %v is read from a %v", name, t))
+			}
+		case *ast.BinaryExpression:
+			if isArithmetic(n.Operator) && (in.Type(n.Left).Is(String) || in.Type(n.Right).Is(String)) {
+				in.report(md, n, StringArithmeticCode, fmt.Sprintf("arithmetic %v on a string", n.Operator))
+			}
+		case *ast.AssignExpression:
+			if isArithmetic(n.Operator) && (in.Type(n.Left).Is(String) || in.Type(n.Right).Is(String)) {
+				in.report(md, n, StringArithmeticCode, fmt.Sprintf("arithmetic %v= on a string", n.Operator))
+			}
+		case *ast.UnaryExpression:
+			if n.Operator == token.MINUS && in.Type(n.Operand).Is(String) {
+				in.report(md, n, StringArithmeticCode, "negation of a string")
+			}
+		}
+	}
+}
+
+func (in *Inferrer) report(md walker.Metadata, node ast.Node, code, message string) {
+	diagnostic := walker.NewDiagnostic(node, walker.Warning, code, message)
+	diagnostic.Locate(md)
+	in.Diagnostics = append(in.Diagnostics, diagnostic)
+}
@@ -0,0 +1,56 @@
+// Package types infers the primitive types of variables and expressions walked by the walker.
+package types
+
+import (
+	"strings"
+)
+
+// Type is a set of primitive types, a union if more than one is set
+type Type uint
+
+const (
+	Number Type = 1 << iota
+	String
+	Boolean
+	// Object includes null, as typeof does
+	Object
+	Function
+	Array
+	Undefined
+	// Unknown means the value can be of any type
+	Unknown
+)
+
+// None is the empty set, the type of expressions not inferred yet
+const None Type = 0
+
+var names = []string{"number", "string", "boolean", "object", "function", "array", "undefined", "unknown"}
+
+// String returns the names of the types separated by |
+func (t Type) String() string {
+	if t&Unknown != 0 {
+		return "unknown"
+	}
+	if t == None {
+		return "none"
+	}
+
+	var parts []string
+	for i, name := range names {
+		if t&(1<<uint(i)) != 0 {
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// Is returns true if the type is known and every type of the union is in the given set
+func (t Type) Is(set Type) bool {
+	return t != None && t&Unknown == 0 && t&^set == 0
+}
+
+// Union returns the union of the types
+func (t Type) Union(other Type) Type {
+	return t | other
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/parser"
+	"github.com/wolfgarnet/walker"
+)
+
+// infer walks the source, returning the inferrer and the first argument of the last call
+func infer(t *testing.T, src string) (*Inferrer, ast.Expression) {
+	program, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		t.Fatalf("Failed, %v", err)
+	}
+
+	inferrer := NewInferrer()
+	var argument ast.Expression
+	visitor := &walker.VisitorImpl{}
+	visitor.AddHook(inferrer.Hook())
+	visitor.AddHook(&walker.Hook{
+		OnNode: func(node ast.Node, metadata []walker.Metadata) error {
+			if call, ok := node.(*ast.CallExpression); ok && len(call.ArgumentList) > 0 {
+				argument = call.ArgumentList[0]
+			}
+			return nil
+		},
+	})
+	walker.NewWalker(visitor).Begin(program)
+
+	return inferrer, argument
+}
+
+func TestType(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`f(1 + 2)`, "number"},
+		{`f("a" + 1)`, "string"},
+		{`f(1 < 2)`, "boolean"},
+		{`f(typeof x)`, "string"},
+		{`f(null)`, "object"},
+		{`f([])`, "array"},
+		{`f(function() {})`, "function"},
+		{`f(a ? 1 : "b")`, "number|string"},
+		{`var x = 1; f(x)`, "number"},
+		{`var x = 1; x = "a"; f(x)`, "number|string"},
+		{`var x; f(x)`, "undefined"},
+		{`var x; x = []; f(x)`, "array"},
+		{`var x = 0; x = x + 1; f(x)`, "number"},
+		{`var x = 0; x += "a"; f(x)`, "number|string"},
+		{`var x = 1, y = x * 2; f(y)`, "number"},
+		{`function g() {} f(g)`, "function"},
+		{`function g(a) { f(a) }`, "unknown"},
+		{`for (var k in o) { f(k) }`, "string"},
+		{`var x = 1; x = g(); f(x)`, "unknown"},
+		{`function g() { var x = 1; eval("x = 'a'"); f(x) }`, "unknown"},
+		{`f(undefined)`, "undefined"},
+		{`f(x)`, "unknown"},
+	}
+
+	for i, test := range tests {
+		inferrer, argument := infer(t, test.src)
+		if result := inferrer.Type(argument).String(); result != test.expected {
+			t.Errorf("[%v] Failed, %v: %v != %v", i, test.src, test.expected, result)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`var x = 1; x.length`, "property-on-primitive property length is read from a number"},
+		{`var x = 1; x.toFixed(2)`, ""},
+		{`var x; x.a`, "property-on-primitive property a is read from undefined"},
+		{`var x = "a"; x.length`, ""},
+		{`var x = "a"; x * 2`, "string-arithmetic arithmetic * on a string"},
+		{`var x = "a", y = 1; y -= x`, "string-arithmetic arithmetic -= on a string"},
+		{`-"a"`, "string-arithmetic negation of a string"},
+		{`var x = 1; x = "a"; x - 1`, ""},
+		{`function g(a) { return a.length - 1 }`, ""},
+	}
+
+	for i, test := range tests {
+		inferrer, _ := infer(t, test.src)
+		var result []string
+		for _, d := range inferrer.Diagnostics {
+			result = append(result, d.Code+" "+d.Message)
+		}
+		if strings.Join(result, ", ") != test.expected {
+			t.Errorf("[%v] Failed, %v: %v != %v", i, test.src, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package walker
+
+import (
+	"fmt"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// UnreachableCode is the code of diagnostics reporting statements that can never execute
+const UnreachableCode = "unreachable"
+
+// Terminates returns true if the statement always completes abruptly,
+// i.e. by return, throw, break or continue.
+func Terminates(statement ast.Statement) bool {
+	switch s := statement.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BranchStatement:
+		return true
+	case *ast.BlockStatement:
+		for _, e := range s.List {
+			if Terminates(e) {
+				return true
+			}
+		}
+	case *ast.IfStatement:
+		return s.Alternate != nil && Terminates(s.Consequent) && Terminates(s.Alternate)
+	case *ast.TryStatement:
+		if s.Finally != nil && Terminates(s.Finally) {
+			return true
+		}
+		return Terminates(s.Body) && (s.Catch == nil || Terminates(s.Catch.Body))
+	}
+
+	return false
+}
+
+// Statements returns the statement list of a program, block or case, nil for other nodes
+func Statements(node ast.Node) []ast.Statement {
+	switch n := node.(type) {
+	case *ast.Program:
+		return n.Body
+	case *ast.BlockStatement:
+		return n.List
+	case *ast.CaseStatement:
+		return n.Consequent
+	}
+
+	return nil
+}
+
+// constantTest returns the value of a literal test, ok is false if the test is not a literal
+func constantTest(test ast.Expression) (value bool, ok bool) {
+	switch t := test.(type) {
+	case *ast.BooleanLiteral:
+		return t.Value, true
+	case *ast.NumberLiteral:
+		switch v := t.Value.(type) {
+		case int64:
+			return v != 0, true
+		case float64:
+			return v != 0, true
+		}
+	}
+
+	return false, false
+}
+
+// UnreachableAnalyzer reports the first statement of code that can never execute:
+// code following return, throw, break and continue, and the branches and loop bodies of constant tests.
+type UnreachableAnalyzer struct {
+	Diagnostics []*Diagnostic
+	// Options of the snippets of the diagnostics
+	Options SnippetOptions
+
+	walker   *Walker
+	reported map[ast.Node]bool
+}
+
+// NewUnreachableAnalyzer returns a new instance, the walker is used for snippets
+func NewUnreachableAnalyzer(walker *Walker) *UnreachableAnalyzer {
+	return &UnreachableAnalyzer{
+		walker:   walker,
+		reported: map[ast.Node]bool{},
+	}
+}
+
+// Hook returns the hook performing the analysis
+func (a *UnreachableAnalyzer) Hook() *Hook {
+	return &Hook{
+		OnNode: a.onNode,
+	}
+}
+
+func (a *UnreachableAnalyzer) onNode(node ast.Node, metadata []Metadata) error {
+	statement, ok := node.(ast.Statement)
+	if !ok {
+		return nil
+	}
+
+	// Only the first statement of unreachable code is reported
+	for _, md := range metadata[:len(metadata)-1] {
+		if a.reported[md.Node()] {
+			return nil
+		}
+	}
+
+	parent := ParentMetadata(metadata).Node()
+	switch p := parent.(type) {
+	case *ast.IfStatement:
+		if value, ok := constantTest(p.Test); ok && (value && statement == p.Alternate || !value && statement == p.Consequent) {
+			a.report(statement, fmt.Sprintf("branch of constant condition %v is never executed", value))
+		}
+		return nil
+	case *ast.WhileStatement:
+		if value, ok := constantTest(p.Test); ok && !value && statement == p.Body {
+			a.report(statement, "body of loop with false condition is never executed")
+		}
+		return nil
+	case *ast.ForStatement:
+		if value, ok := constantTest(p.Test); ok && !value && statement == p.Body {
+			a.report(statement, "body of loop with false condition is never executed")
+		}
+		return nil
+	}
+
+	terminated := false
+	for _, sibling := range Statements(parent) {
+		if sibling == statement {
+			break
+		}
+		if a.reported[sibling] {
+			return nil
+		}
+		terminated = terminated || Terminates(sibling)
+	}
+
+	if terminated && !isDeclaration(statement) {
+		a.report(statement, "code is never executed")
+	}
+
+	return nil
+}
+
+// isDeclaration returns true if the statement has no effect where it is placed,
+// i.e. function declarations and variable declarations without initializers, which are hoisted.
+func isDeclaration(statement ast.Statement) bool {
+	switch s := statement.(type) {
+	case *ast.FunctionStatement:
+		return true
+	case *ast.VariableStatement:
+		for _, e := range s.List {
+			if v, ok := e.(*ast.VariableExpression); !ok || v.Initializer != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (a *UnreachableAnalyzer) report(statement ast.Statement, message string) {
+	a.reported[statement] = true
+	diagnostic := NewDiagnostic(statement, Warning, UnreachableCode, message)
+	diagnostic.Snippet = a.walker.Snippet(statement, a.Options)
+	a.walker.Locate(diagnostic)
+	a.Diagnostics = append(a.Diagnostics, diagnostic)
+}
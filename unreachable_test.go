@@ -0,0 +1,51 @@
+package walker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/parser"
+)
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		src         string
+		diagnostics []string
+	}{
+		{`function f() { a(); return; b(); c() }`, []string{`code is never executed @1:29`}},
+		{`function f() { throw a; function g() {} var h; g() }`, []string{`code is never executed @1:48`}},
+		{`while (a) { if (b) { continue } else { break } c() }`, []string{`code is never executed @1:48`}},
+		{`switch (a) { case 1: break; b(); case 2: c() }`, []string{`code is never executed @1:29`}},
+		{`if (false) { a() } else { b() }`, []string{`branch of constant condition false is never executed @1:12`}},
+		{`if (1) { a() } else { b() }`, []string{`branch of constant condition true is never executed @1:21`}},
+		{`while (false) { a(); break; b() }`, []string{`body of loop with false condition is never executed @1:15`}},
+		{`for (;0;) a()`, []string{`body of loop with false condition is never executed @1:11`}},
+		{`function f() { if (a) { return } b() }`, nil},
+		{`function f() { try { return } finally { a() } b() }`, []string{`code is never executed @1:47`}},
+		{`function f() { var a; return a; }`, nil},
+	}
+
+	for i, test := range tests {
+		program, err := parser.ParseFile(nil, "", test.src, 0)
+		if err != nil {
+			t.Errorf("[%v] Failed, %v", i, err)
+			continue
+		}
+
+		visitor := &VisitorImpl{}
+		walker := NewWalker(visitor)
+		analyzer := NewUnreachableAnalyzer(walker)
+		visitor.AddHook(analyzer.Hook())
+		walker.Begin(program)
+
+		var messages []string
+		for _, diagnostic := range analyzer.Diagnostics {
+			position := strings.SplitN(strings.TrimSpace(diagnostic.Snippet), "\n", 2)[0]
+			messages = append(messages, diagnostic.Message+" @"+strings.TrimPrefix(position, "--> <source>:"))
+		}
+		if !reflect.DeepEqual(test.diagnostics, messages) {
+			t.Errorf("[%v] Failed, diagnostics not correct, %v != %v", i, test.diagnostics, messages)
+		}
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"github.com/robertkrimen/otto/ast"
 	"github.com/robertkrimen/otto/file"
 	"runtime/debug"
-"strings"
 )
 
 type Hook struct {
 	OnNode      func(node ast.Node, metadata []Metadata) error
 	OnNodeLeave func(node ast.Node, metadata []Metadata) error
+	// OnComment is called after OnNode for each comment attached to the node
+	OnComment func(comment *ast.Comment, metadata []Metadata) error
 
 	OnFinished func(node ast.Node, metadata Metadata) error
 }
@@ -22,6 +23,13 @@ type Walker struct {
 	CatchPanic      bool
 	program         *ast.Program
 	OnFailed        func(node ast.Node, program *ast.Program)
+	// File is the source of the walked nodes, by default the file of the walked program
+	File *file.File
+	// Comments are the comments of the walked nodes, by default the comments of the walked program
+	Comments ast.CommentMap
+	// SourceMap maps the positions of the file to the original sources, positions not mapped are reported in the file.
+	// It replaces a source map given to the parser, e.g. by parser.ParseFileWithSourceMap, which would map them twice.
+	SourceMap *SourceMap
 }
 
 func NewWalker(visitor Visitor) *Walker {
@@ -84,12 +92,18 @@ type Visitor interface {
 	ResetHooks()
 }
 
+// GetPosition returns the position of the index in the original source if it is mapped, else in the file.
+// Nil is returned if the file is not known.
 func (w *Walker) GetPosition(idx file.Idx) *file.Position {
-	if w.program == nil {
-		return nil
-	}
+	return position(w.file(), w.SourceMap, idx)
+}
 
-	return w.program.File.Position(idx)
+// Locate sets the positions of the diagnostics from their nodes
+func (w *Walker) Locate(diagnostics ...*Diagnostic) {
+	md := Metadata{FileField: w.file(), SourceMapField: w.SourceMap}
+	for _, diagnostic := range diagnostics {
+		diagnostic.Locate(md)
+	}
 }
 
 // Begin the walk of the given AST node
@@ -135,46 +149,6 @@ func (w *Walker) Begin(node ast.Node) {
 	}
 }
 
-var (
-	snippetLength int  = 50
-	useNewLines   bool = true
-)
-
-func (w *Walker) Snippet(node ast.Node, before, after file.Idx) string {
-	program, isProgram := w.Root.(*ast.Program)
-	if !isProgram {
-		return "N/A"
-	}
-
-	var snippet string
-	switch w.Root.(type) {
-	case *ast.Program, *ast.FunctionLiteral, *ast.FunctionStatement, *ast.BlockStatement, *ast.ExpressionStatement, *ast.CallExpression:
-		diff := node.Idx1() - node.Idx0()
-		end := node.Idx1() - 1
-		if int(diff) > snippetLength {
-			end = node.Idx0() + file.Idx(snippetLength)
-		}
-		snippet = program.File.Source()[node.Idx0()-1-before:end+after] + " ..."
-
-	default:
-		before = node.Idx0() - 1 - before
-		if before < 0 {
-			before = 0
-		}
-		after = node.Idx1() - 1 + after
-		if after > program.Idx1() {
-			after = program.Idx1()
-		}
-		snippet = program.File.Source()[before:after]
-	}
-
-	position := program.File.Position(node.Idx0() + 1)
-	if !useNewLines {
-		snippet = strings.Replace(snippet, "\n", " ", -1)
-	}
-	return fmt.Sprintf("%v,%v: \"%v\"", position.Line, position.Column, snippet)
-}
-
 // CollectScope collects information about the given scope
 func CollectScope(metadata Metadata, declarations []ast.Declaration) {
 	// Initialize the scope variables field in the metadata
@@ -190,28 +164,41 @@ func CollectScope(metadata Metadata, declarations []ast.Declaration) {
 			for _, v := range d.List {
 				vars[v.Name] = v.Idx
 			}
+		case *ast.FunctionDeclaration:
+			if d.Function.Name != nil {
+				vars[d.Function.Name.Name] = d.Function.Name.Idx
+			}
 		}
 	}
 }
 
-func FindVariable(metadata []Metadata, name string) file.Idx {
-	md := metadata[len(metadata)-1]
+// CollectIdentifiers adds the given identifiers, e.g. parameters, to the scope
+func CollectIdentifiers(metadata Metadata, identifiers []*ast.Identifier) {
+	vars, ok := metadata[Vars].(Variables)
+	if !ok {
+		vars = NewVariables()
+		metadata[Vars] = vars
+	}
 
-	vars, ok := md[Vars].(Variables)
-	if ok {
-		for v, i := range vars {
-			if v == name {
-				return i
-			}
+	for _, identifier := range identifiers {
+		if identifier != nil {
+			vars[identifier.Name] = identifier.Idx
 		}
 	}
+}
 
-	if len(metadata) > 1 {
-		metadata = metadata[:len(metadata)-1]
-		return FindVariable(metadata, name)
+// FindVariable returns the index of the declaration of the variable, -1 if not found.
+// Unknown is returned if a dynamic scope is met before the declaration.
+func FindVariable(metadata []Metadata, name string) file.Idx {
+	i, unknown := resolveVariable(metadata, name)
+	switch {
+	case i < 0:
+		return -1
+	case unknown:
+		return Unknown
 	}
 
-	return -1
+	return metadata[i][Vars].(Variables)[name]
 }
 
 // Walk the AST, including metadata
@@ -221,13 +208,48 @@ func (w *Walker) Walk(node ast.Node, metadata []Metadata) (result Metadata) {
 
 	// Create metadata for current node
 	md := NewMetadata(node)
+	if f := w.file(); f != nil {
+		md[FileField] = f
+	}
+	if w.SourceMap != nil {
+		md[SourceMapField] = w.SourceMap
+	}
+	if comments := w.comments()[node]; len(comments) > 0 {
+		md[CommentsField] = comments
+	}
 
 	// Scope things
 	switch n := node.(type) {
 	case *ast.Program:
 		CollectScope(md, n.DeclarationList)
+		if ContainsEval(n, append(metadata, md)) {
+			md[Dynamic] = true
+		}
+		md[Strict] = HasUseStrict(n.Body)
 	case *ast.FunctionLiteral:
+		// The name of a function expression is only visible inside the function itself
+		if _, isStatement := CurrentMetadata(metadata).Node().(*ast.FunctionStatement); !isStatement && n.Name != nil {
+			CollectIdentifiers(md, []*ast.Identifier{n.Name})
+		}
 		CollectScope(md, n.DeclarationList)
+		if n.ParameterList != nil {
+			CollectIdentifiers(md, n.ParameterList.List)
+		}
+		if ContainsEval(n, append(metadata, md)) {
+			md[Dynamic] = true
+		}
+		md[Strict] = IsStrict(metadata)
+		if body, ok := n.Body.(*ast.BlockStatement); ok && HasUseStrict(body.List) {
+			md[Strict] = true
+		}
+	case *ast.CatchStatement:
+		CollectIdentifiers(md, []*ast.Identifier{n.Parameter})
+	case *ast.WithStatement:
+		md[Dynamic] = true
+	case *ast.BranchStatement:
+		if target, _ := FindBranchTarget(metadata, n); target != nil {
+			md[Target] = target
+		}
 	}
 
 	// Append the node
@@ -238,6 +260,13 @@ func (w *Walker) Walk(node ast.Node, metadata []Metadata) (result Metadata) {
 			hook.OnNode(node, metadata)
 		}
 	}
+	for _, comment := range md.Comments().All() {
+		for _, hook := range w.Visitor.getHooks() {
+			if hook.OnComment != nil {
+				hook.OnComment(comment, metadata)
+			}
+		}
+	}
 
 	switch n := node.(type) {
 	case *ast.ArrayLiteral: